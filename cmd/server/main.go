@@ -1,13 +1,67 @@
 package main
 
 import (
-	"fmt"
-	"log"
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/integralist/integralist.co.uk/internal/server"
 )
 
 func main() {
-	addr := ":8080"
-	fmt.Printf("Serving at http://localhost%s\n", addr)
-	log.Fatal(http.ListenAndServe(addr, http.FileServer(http.Dir("public"))))
+	addr := flag.String("addr", cmp.Or(os.Getenv("SSG_ADDR"), ":8080"), "address to listen on (env SSG_ADDR)")
+	root := flag.String("root", cmp.Or(os.Getenv("SSG_ROOT"), "public"), "directory to serve (env SSG_ROOT)")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := run(*addr, *root, logger); err != nil {
+		logger.Error("server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(addr, root string, logger *slog.Logger) error {
+	site, err := server.New(root)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           server.AccessLog(logger, site),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		logger.Info("serving", "addr", addr, "root", root)
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
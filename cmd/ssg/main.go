@@ -1,16 +1,70 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 
 	"github.com/integralist/integralist.co.uk/internal/builder"
+	"github.com/integralist/integralist.co.uk/internal/config"
+	"github.com/integralist/integralist.co.uk/internal/stats"
 )
 
 func main() {
-	b := builder.New("content", "assets", "public", "https://www.integralist.co.uk")
+	cfg, err := config.Load("site.yaml")
+	if err != nil {
+		log.Fatal(err)
+	}
+	b := builder.New("content", "assets", "public", "https://www.integralist.co.uk", builder.WithConfig(cfg))
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "stats":
+			if err := report(b, os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			os.Exit(0)
+		default:
+			log.Fatalf("unknown command %q (want no command to build, or stats)", os.Args[1])
+		}
+	}
+
 	if err := b.Build(); err != nil {
 		log.Fatal(err)
 	}
 	os.Exit(0)
 }
+
+// report prints statistics about the site, and writes them as JSON to the
+// file given by the -json flag. It renders pages in memory and never writes
+// to the output directory.
+func report(b *builder.Builder, args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	jsonFile := flags.String("json", "", "also write the report as JSON to `file`")
+	flags.Parse(args)
+
+	site, sizes, err := b.Preview()
+	if err != nil {
+		return err
+	}
+	r, err := stats.New(site, sizes)
+	if err != nil {
+		return err
+	}
+	if err := r.WriteText(os.Stdout); err != nil {
+		return err
+	}
+
+	if *jsonFile != "" {
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*jsonFile, append(data, '\n'), 0o644); err != nil {
+			return fmt.Errorf("writing %s: %w", *jsonFile, err)
+		}
+	}
+	return nil
+}
@@ -3,6 +3,13 @@ module github.com/integralist/integralist.co.uk
 go 1.26.2
 
 require (
+	github.com/andybalholm/brotli v1.2.6
 	github.com/gomarkdown/markdown v0.0.0-20260412113850-134a5b2cce7f
 	gopkg.in/yaml.v3 v3.0.1
 )
+
+require (
+	golang.org/x/image v0.46.0
+	golang.org/x/sys v0.48.0 // indirect
+	golang.org/x/text v0.42.0 // indirect
+)
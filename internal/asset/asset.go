@@ -0,0 +1,151 @@
+// Package asset copies static assets into the output directory, giving
+// stylesheets and scripts content-hashed names so they can be cached forever.
+package asset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// fingerprinted are the extensions that get a content hash in their name.
+// Images are left alone, since posts link to them by path.
+var fingerprinted = map[string]bool{
+	".css": true,
+	".js":  true,
+	".mjs": true,
+}
+
+// Manifest maps asset paths relative to the assets directory (e.g.
+// css/style.css) to the URLs they are served from (e.g.
+// /assets/css/style.3f9a1c2b.css).
+type Manifest map[string]string
+
+// Option configures Copy.
+type Option func(*options)
+
+type options struct {
+	transforms map[string]func([]byte) []byte
+}
+
+// WithTransform rewrites files with extension ext (e.g. ".css") through fn
+// as they are copied, before any content hash is taken, so that the hash
+// matches the bytes served.
+func WithTransform(ext string, fn func([]byte) []byte) Option {
+	return func(o *options) {
+		o.transforms[ext] = fn
+	}
+}
+
+// Copy copies every file in srcDir, except the templates directory, to
+// dstDir/assets, and returns the resulting Manifest.
+func Copy(srcDir, dstDir string, opts ...Option) (Manifest, error) {
+	o := options{transforms: make(map[string]func([]byte) []byte)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	m := make(Manifest)
+	err := filepath.WalkDir(srcDir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(srcDir, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel == "templates" && d.IsDir() {
+			return filepath.SkipDir
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		ext := path.Ext(rel)
+		transform := o.transforms[ext]
+		if transform == nil && !fingerprinted[ext] {
+			m[rel] = "/assets/" + rel
+			return copyFile(file, filepath.Join(dstDir, "assets", filepath.FromSlash(rel)))
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if transform != nil {
+			data = transform(data)
+		}
+		name := rel
+		if fingerprinted[ext] {
+			sum := sha256.Sum256(data)
+			name = strings.TrimSuffix(rel, ext) + "." + hex.EncodeToString(sum[:4]) + ext
+		}
+		if err := writeFile(filepath.Join(dstDir, "assets", filepath.FromSlash(name)), data); err != nil {
+			return err
+		}
+		m[rel] = "/assets/" + name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// URL returns the URL for the asset at path, relative to the assets
+// directory.
+func (m Manifest) URL(file string) (string, error) {
+	url, ok := m[strings.TrimPrefix(file, "/")]
+	if !ok {
+		return "", fmt.Errorf("unknown asset %q", file)
+	}
+	return url, nil
+}
+
+// Fingerprinted returns the entries of m that have content-hashed names.
+func (m Manifest) Fingerprinted() Manifest {
+	out := make(Manifest)
+	for rel, url := range m {
+		if url != "/assets/"+rel {
+			out[rel] = url
+		}
+	}
+	return out
+}
+
+func writeFile(dst string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0o644)
+}
+
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
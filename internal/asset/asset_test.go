@@ -0,0 +1,97 @@
+package asset_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/integralist/integralist.co.uk/internal/asset"
+)
+
+func TestCopy(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	os.MkdirAll(filepath.Join(src, "css"), 0o755)
+	os.MkdirAll(filepath.Join(src, "img"), 0o755)
+	os.MkdirAll(filepath.Join(src, "templates"), 0o755)
+	os.WriteFile(filepath.Join(src, "css", "style.css"), []byte("body { margin: 0; }"), 0o644)
+	os.WriteFile(filepath.Join(src, "img", "hero.png"), []byte("png"), 0o644)
+	os.WriteFile(filepath.Join(src, "templates", "base.html"), []byte("<html>"), 0o644)
+
+	m, err := asset.Copy(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	css, err := m.URL("css/style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^/assets/css/style\.[0-9a-f]{8}\.css$`).MatchString(css) {
+		t.Errorf("css URL = %q, want fingerprinted name", css)
+	}
+	if _, err := os.Stat(filepath.Join(dst, filepath.FromSlash(css))); err != nil {
+		t.Errorf("fingerprinted file not written: %v", err)
+	}
+
+	if img, _ := m.URL("img/hero.png"); img != "/assets/img/hero.png" {
+		t.Errorf("image URL = %q, want unchanged path", img)
+	}
+	if _, err := m.URL("templates/base.html"); err == nil {
+		t.Error("templates copied as assets")
+	}
+
+	fp := m.Fingerprinted()
+	if len(fp) != 1 || fp["css/style.css"] != css {
+		t.Errorf("Fingerprinted() = %v, want only the stylesheet", fp)
+	}
+}
+
+// Verifies that a content change produces a new name.
+func TestCopy_HashChangesWithContent(t *testing.T) {
+	src := t.TempDir()
+	os.WriteFile(filepath.Join(src, "app.js"), []byte("one()"), 0o644)
+	first, err := asset.Copy(src, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(filepath.Join(src, "app.js"), []byte("two()"), 0o644)
+	second, err := asset.Copy(src, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first["app.js"] == second["app.js"] {
+		t.Errorf("URL %q unchanged after content change", first["app.js"])
+	}
+}
+
+// Verifies that a transformed file is written and fingerprinted as
+// transformed, so its name matches the bytes served.
+func TestCopy_Transform(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	os.WriteFile(filepath.Join(src, "app.css"), []byte("a { color: red; }"), 0o644)
+
+	m, err := asset.Copy(src, dst, asset.WithTransform(".css", func(data []byte) []byte {
+		return []byte("a{color:red}")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("a{color:red}"))
+	want := "/assets/app." + hex.EncodeToString(sum[:4]) + ".css"
+	if m["app.css"] != want {
+		t.Errorf("URL = %q, want %q", m["app.css"], want)
+	}
+	data, err := os.ReadFile(filepath.Join(dst, filepath.FromSlash(want)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a{color:red}" {
+		t.Errorf("written = %q, want the transformed content", data)
+	}
+}
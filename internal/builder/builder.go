@@ -1,35 +1,61 @@
 package builder
 
 import (
+	"bytes"
+	"cmp"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
-	"io"
-	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	texttemplate "text/template"
+	"time"
 
+	"github.com/integralist/integralist.co.uk/internal/asset"
+	"github.com/integralist/integralist.co.uk/internal/bundle"
+	"github.com/integralist/integralist.co.uk/internal/compress"
+	"github.com/integralist/integralist.co.uk/internal/config"
 	"github.com/integralist/integralist.co.uk/internal/content"
+	"github.com/integralist/integralist.co.uk/internal/minify"
 	"github.com/integralist/integralist.co.uk/internal/model"
+	"github.com/integralist/integralist.co.uk/internal/parser"
 	"github.com/integralist/integralist.co.uk/internal/renderer"
 )
 
 // Builder orchestrates the static site build.
 type Builder struct {
 	baseURL    string
+	config     config.Config
 	contentDir string
 	assetsDir  string
 	outputDir  string
 }
 
+// Option configures a Builder.
+type Option func(*Builder)
+
+// WithConfig sets the site configuration. Without it, config.Default is used.
+func WithConfig(cfg config.Config) Option {
+	return func(b *Builder) {
+		b.config = cfg
+	}
+}
+
 // New creates a Builder.
-func New(contentDir, assetsDir, outputDir, baseURL string) *Builder {
-	return &Builder{
+func New(contentDir, assetsDir, outputDir, baseURL string, opts ...Option) *Builder {
+	b := &Builder{
 		baseURL:    baseURL,
+		config:     config.Default(),
 		contentDir: contentDir,
 		assetsDir:  assetsDir,
 		outputDir:  outputDir,
 	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
 }
 
 // Build generates the static site.
@@ -38,22 +64,35 @@ func (b *Builder) Build() error {
 		return fmt.Errorf("clean: %w", err)
 	}
 
-	if err := b.copyAssets(); err != nil {
+	assets, css, err := b.copyAssets()
+	if err != nil {
 		return fmt.Errorf("copy assets: %w", err)
 	}
 
-	site, err := content.LoadSite(b.contentDir)
+	templateDir := filepath.Join(b.assetsDir, "templates")
+	site, bundles, err := b.load(templateDir)
 	if err != nil {
-		return fmt.Errorf("load content: %w", err)
+		return err
+	}
+	for _, w := range site.Warnings {
+		fmt.Printf("Warning: %s\n", w)
 	}
-	site.BaseURL = b.baseURL
 
-	templateDir := filepath.Join(b.assetsDir, "templates")
-	r, err := renderer.New(templateDir)
+	if err := bundles.Resolve(assets); err != nil {
+		return fmt.Errorf("resolve bundles: %w", err)
+	}
+
+	r, err := b.newRenderer(templateDir, bundles, renderer.WithAssets(assets))
 	if err != nil {
 		return fmt.Errorf("init renderer: %w", err)
 	}
 
+	if b.config.Cards.Enabled {
+		if err := b.generateCards(site); err != nil {
+			return fmt.Errorf("cards: %w", err)
+		}
+	}
+
 	if err := b.renderSite(r, site); err != nil {
 		return fmt.Errorf("render: %w", err)
 	}
@@ -61,11 +100,200 @@ func (b *Builder) Build() error {
 	if err := b.generateDiscoveryFiles(site); err != nil {
 		return fmt.Errorf("discovery files: %w", err)
 	}
+	if err := b.generateLlmsFull(site); err != nil {
+		return fmt.Errorf("llms-full.txt: %w", err)
+	}
+	if err := b.generateTermFeeds(site); err != nil {
+		return fmt.Errorf("feeds: %w", err)
+	}
+
+	if err := b.generateHeaders(assets, site); err != nil {
+		return fmt.Errorf("headers: %w", err)
+	}
+	if err := b.generateRedirects(site); err != nil {
+		return fmt.Errorf("redirects: %w", err)
+	}
 
 	fmt.Printf("Built %d posts, %d pages, %d tags\n", len(site.Posts), len(site.Pages), len(site.Tags))
+
+	if b.config.Minify {
+		if err := b.minify(css); err != nil {
+			return fmt.Errorf("minify: %w", err)
+		}
+	}
+	if c := b.config.Compress; c.Enabled {
+		res, err := compress.Dir(b.outputDir, compress.Options{MinSize: c.MinSize, Brotli: c.Brotli})
+		if err != nil {
+			return fmt.Errorf("compress: %w", err)
+		}
+		fmt.Printf("Compressed %d gzip, %d brotli files\n", res.Gzip, res.Brotli)
+	}
+	return nil
+}
+
+// Preview loads the site and renders its HTML pages in memory, as Build
+// would but without touching the output directory. It returns the site and
+// the size in bytes of each page by URL, minified if minification is
+// enabled. Asset URLs aren't fingerprinted and cards aren't drawn, so sizes
+// can differ from a real build by a few bytes.
+func (b *Builder) Preview() (*model.Site, map[string]int, error) {
+	templateDir := filepath.Join(b.assetsDir, "templates")
+	site, bundles, err := b.load(templateDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := b.newRenderer(templateDir, bundles)
+	if err != nil {
+		return nil, nil, fmt.Errorf("init renderer: %w", err)
+	}
+
+	sizes := make(map[string]int)
+	err = renderPages(r, site, func(file string, html []byte) error {
+		if b.config.Minify {
+			html = minify.HTML(html)
+		}
+		sizes["/"+strings.TrimSuffix(file, "index.html")] = len(html)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("render: %w", err)
+	}
+	return site, sizes, nil
+}
+
+// load reads the site's content, rendering Markdown with the configured
+// options and checking it against the configured bundles.
+func (b *Builder) load(templateDir string) (*model.Site, *bundle.Registry, error) {
+	converter, err := parser.NewConverter(b.markdownOptions(templateDir))
+	if err != nil {
+		return nil, nil, fmt.Errorf("init markdown: %w", err)
+	}
+
+	bundles, err := bundle.Load(templateDir, b.bundleDefinitions())
+	if err != nil {
+		return nil, nil, fmt.Errorf("load bundles: %w", err)
+	}
+	if b.config.Markdown.Diagrams.Enabled {
+		// Diagrams are already SVG, so posts asking for mermaid need nothing
+		// from the CDN.
+		bundles.Disable("mermaid")
+	}
+
+	opts := []content.Option{
+		content.WithConverter(converter),
+		content.WithBundles(bundles),
+		content.WithSummaryWords(b.config.Summary.Words),
+		content.WithReadingSpeed(model.ReadingSpeed{
+			WordsPerMinute:     b.config.Reading.WordsPerMinute,
+			SecondsPerCodeLine: b.config.Reading.SecondsPerCodeLine,
+			SecondsPerImage:    b.config.Reading.SecondsPerImage,
+		}),
+	}
+	if b.config.Updated.Git {
+		opts = append(opts, content.WithGitUpdated())
+	}
+	if len(b.config.Taxonomies) > 0 {
+		taxonomies := make([]model.Taxonomy, 0, len(b.config.Taxonomies))
+		for _, t := range b.config.Taxonomies {
+			taxonomies = append(taxonomies, model.Taxonomy{Key: t.Key, Singular: t.Singular, Title: t.Title})
+		}
+		opts = append(opts, content.WithTaxonomies(taxonomies...))
+	}
+	site, err := content.LoadSite(b.contentDir, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load content: %w", err)
+	}
+	site.BaseURL = b.baseURL
+	return site, bundles, nil
+}
+
+func (b *Builder) newRenderer(templateDir string, bundles *bundle.Registry, opts ...renderer.Option) (*renderer.Renderer, error) {
+	return renderer.New(templateDir, append([]renderer.Option{
+		renderer.WithBundles(bundles),
+		renderer.WithSearchURL(b.config.StructuredData.SearchURL),
+	}, opts...)...)
+}
+
+// minify shrinks the generated HTML in place and reports the bytes saved for
+// each file type, with css the result of minifying stylesheets as they were
+// copied.
+func (b *Builder) minify(css minify.Result) error {
+	results, err := minify.Dir(b.outputDir, ".html")
+	if err != nil {
+		return err
+	}
+	if css.Files > 0 {
+		results["css"] = css
+	}
+
+	kinds := make([]string, 0, len(results))
+	for kind := range results {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		r := results[kind]
+		pct := 0.0
+		if r.Before > 0 {
+			pct = float64(r.Saved()) / float64(r.Before) * 100
+		}
+		fmt.Printf("Minified %d %s files: saved %s (%.1f%%)\n", r.Files, kind, formatBytes(r.Saved()), pct)
+	}
 	return nil
 }
 
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+func (b *Builder) markdownOptions(templateDir string) parser.Options {
+	md := b.config.Markdown
+	opts := parser.Options{
+		Alerts:             md.Alerts,
+		FootnoteReturnLink: md.Footnotes.ReturnLink,
+		ShortcodeDir:       filepath.Join(templateDir, "shortcodes"),
+	}
+	if md.Anchors.Enabled {
+		opts.Anchors = parser.AnchorOptions{
+			MinLevel: md.Anchors.MinLevel,
+			MaxLevel: md.Anchors.MaxLevel,
+			Class:    md.Anchors.Class,
+			Symbol:   md.Anchors.Symbol,
+			Before:   md.Anchors.Position == "before",
+		}
+	}
+	if md.Diagrams.Enabled {
+		var r parser.DiagramRenderer = &parser.CommandRenderer{
+			Command: md.Diagrams.Command,
+			Timeout: md.Diagrams.Timeout,
+		}
+		if md.Diagrams.CacheDir != "" {
+			r = &parser.CachedRenderer{
+				Renderer: r,
+				Dir:      md.Diagrams.CacheDir,
+				Key:      strings.Join(md.Diagrams.Command, "\x00"),
+			}
+		}
+		opts.Diagrams = r
+	}
+	return opts
+}
+
+func (b *Builder) bundleDefinitions() map[string]bundle.Definition {
+	defs := make(map[string]bundle.Definition, len(b.config.Bundles))
+	for name, def := range b.config.Bundles {
+		defs[name] = bundle.Definition{Scripts: def.Scripts, Styles: def.Styles}
+	}
+	return defs
+}
+
 func (b *Builder) clean() error {
 	if err := os.RemoveAll(b.outputDir); err != nil {
 		return err
@@ -73,175 +301,302 @@ func (b *Builder) clean() error {
 	return os.MkdirAll(b.outputDir, 0o755)
 }
 
-func (b *Builder) copyAssets() error {
-	return filepath.WalkDir(b.assetsDir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
+// copyAssets copies the assets to the output directory. With minify on,
+// stylesheets are minified as they are copied, so their fingerprint is that
+// of the bytes served; the bytes saved are returned.
+func (b *Builder) copyAssets() (asset.Manifest, minify.Result, error) {
+	var css minify.Result
+	var opts []asset.Option
+	if b.config.Minify {
+		opts = append(opts, asset.WithTransform(".css", func(data []byte) []byte {
+			min := minify.CSS(data)
+			css.Files++
+			css.Before += int64(len(data))
+			css.After += int64(len(min))
+			return min
+		}))
+	}
+	assets, err := asset.Copy(b.assetsDir, b.outputDir, opts...)
+	if err != nil {
+		return nil, css, err
+	}
 
-		rel, err := filepath.Rel(b.assetsDir, path)
-		if err != nil {
-			return err
-		}
+	data, err := json.MarshalIndent(assets.Fingerprinted(), "", "  ")
+	if err != nil {
+		return nil, css, err
+	}
+	if err := writeFile(filepath.Join(b.outputDir, "assets", "manifest.json"), append(data, '\n')); err != nil {
+		return nil, css, err
+	}
+	return assets, css, nil
+}
+
+// generateHeaders writes a Netlify _headers file. It tells browsers and CDNs
+// to cache fingerprinted assets forever, since a change gives them a new
+// name, and advertises each page's Markdown companion, which the markdown
+// edge function serves to clients that ask for it.
+func (b *Builder) generateHeaders(assets asset.Manifest, site *model.Site) error {
+	var urls []string
+	for _, url := range assets.Fingerprinted() {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	var buf strings.Builder
+	for _, url := range urls {
+		buf.WriteString(url + "\n  Cache-Control: public, max-age=31536000, immutable\n")
+	}
 
-		// Skip templates directory
-		if strings.HasPrefix(rel, "templates") {
-			if d.IsDir() {
-				return filepath.SkipDir
+	var pages []string
+	for _, post := range site.Posts {
+		pages = append(pages, post.URL)
+	}
+	for _, page := range site.Pages {
+		pages = append(pages, page.URL)
+	}
+	for _, url := range pages {
+		buf.WriteString(url + "\n")
+		buf.WriteString("  Link: <" + url + "index.md>; rel=\"alternate\"; type=\"text/markdown\"\n")
+		buf.WriteString("  Vary: Accept\n")
+	}
+	return writeFile(filepath.Join(b.outputDir, "_headers"), []byte(buf.String()))
+}
+
+// generateRedirects writes a Netlify _redirects file sending the URL of each
+// term's aliases, such as a tag's, to the term. Nothing is written if there
+// are no aliases.
+func (b *Builder) generateRedirects(site *model.Site) error {
+	var buf strings.Builder
+	for _, tax := range site.Taxonomies {
+		for _, term := range tax.Terms {
+			for _, alias := range term.Aliases {
+				if slug := model.Slugify(alias); slug != term.Slug {
+					fmt.Fprintf(&buf, "%s%s/ %s 301\n", tax.URL(), slug, term.URL)
+				}
 			}
-			return nil
 		}
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	return writeFile(filepath.Join(b.outputDir, "_redirects"), []byte(buf.String()))
+}
 
-		dest := filepath.Join(b.outputDir, "assets", rel)
+func (b *Builder) renderSite(r *renderer.Renderer, site *model.Site) error {
+	err := renderPages(r, site, func(file string, html []byte) error {
+		return writeFile(filepath.Join(b.outputDir, filepath.FromSlash(file)), html)
+	})
+	if err != nil {
+		return err
+	}
 
-		if d.IsDir() {
-			return os.MkdirAll(dest, 0o755)
+	// Markdown companions
+	for _, post := range site.Posts {
+		md, err := companion(b.config.Companions.FrontMatter, post.SourceMD, companionHeader{
+			Date:        post.Date,
+			Description: post.Description,
+			Tags:        post.Tags,
+			Title:       post.Title,
+			URL:         site.BaseURL + post.URL,
+		})
+		if err != nil {
+			return fmt.Errorf("companion for post %s: %w", post.Slug, err)
+		}
+		if err := writeFile(filepath.Join(b.outputDir, "posts", post.Slug, "index.md"), md); err != nil {
+			return err
 		}
+	}
+	for _, page := range site.Pages {
+		md, err := companion(b.config.Companions.FrontMatter, page.SourceMD, companionHeader{
+			Description: page.Description,
+			Title:       page.Title,
+			URL:         site.BaseURL + page.URL,
+		})
+		if err != nil {
+			return fmt.Errorf("companion for page %s: %w", page.Slug, err)
+		}
+		if err := writeFile(filepath.Join(b.outputDir, filepath.FromSlash(page.Slug), "index.md"), md); err != nil {
+			return err
+		}
+	}
 
-		return copyFile(path, dest)
-	})
+	// The list of slugs the 404 page suggests from
+	if !r.HasNotFound() {
+		return nil
+	}
+	if err := b.generateSlugList(site); err != nil {
+		return fmt.Errorf("slug list: %w", err)
+	}
+	return nil
 }
 
-func (b *Builder) renderSite(r *renderer.Renderer, site *model.Site) error {
+// renderPages renders every HTML page of the site, passing each to emit
+// with its slash-separated path relative to the output directory.
+func renderPages(r *renderer.Renderer, site *model.Site, emit func(file string, html []byte) error) error {
 	// Homepage
 	html, err := r.RenderHome(site)
 	if err != nil {
 		return fmt.Errorf("render home: %w", err)
 	}
-	if err := writeFile(filepath.Join(b.outputDir, "index.html"), html); err != nil {
+	if err := emit("index.html", html); err != nil {
 		return err
 	}
 
 	// Posts
 	for _, post := range site.Posts {
-		dir := filepath.Join(b.outputDir, "posts", post.Slug)
 		html, err := r.RenderPost(post, site)
 		if err != nil {
 			return fmt.Errorf("render post %s: %w", post.Slug, err)
 		}
-		if err := writeFile(filepath.Join(dir, "index.html"), html); err != nil {
-			return err
-		}
-		if err := writeFile(filepath.Join(dir, "index.md"), post.SourceMD); err != nil {
+		if err := emit("posts/"+post.Slug+"/index.html", html); err != nil {
 			return err
 		}
 	}
 
 	// Pages
 	for _, page := range site.Pages {
-		dir := filepath.Join(b.outputDir, page.Slug)
 		html, err := r.RenderPage(page, site)
 		if err != nil {
 			return fmt.Errorf("render page %s: %w", page.Slug, err)
 		}
-		if err := writeFile(filepath.Join(dir, "index.html"), html); err != nil {
-			return err
-		}
-		if err := writeFile(filepath.Join(dir, "index.md"), page.SourceMD); err != nil {
+		if err := emit(page.Slug+"/index.html", html); err != nil {
 			return err
 		}
 	}
 
-	// Tags index
-	html, err = r.RenderTagsIndex(site)
-	if err != nil {
-		return fmt.Errorf("render tags index: %w", err)
-	}
-	if err := writeFile(filepath.Join(b.outputDir, "tags", "index.html"), html); err != nil {
-		return err
-	}
-
-	// Individual tag pages
-	for _, tag := range site.Tags {
-		html, err := r.RenderTagPage(tag, site)
+	// Taxonomy indexes and term pages, e.g. /tags/ and /tags/go/
+	for _, tax := range site.Taxonomies {
+		html, err := r.RenderTaxonomyIndex(tax, site)
 		if err != nil {
-			return fmt.Errorf("render tag %s: %w", tag.Slug, err)
+			return fmt.Errorf("render %s index: %w", tax.Key, err)
 		}
-		if err := writeFile(filepath.Join(b.outputDir, "tags", tag.Slug, "index.html"), html); err != nil {
+		if err := emit(tax.Key+"/index.html", html); err != nil {
 			return err
 		}
+		for _, term := range tax.Terms {
+			html, err := r.RenderTermPage(tax, term, site)
+			if err != nil {
+				return fmt.Errorf("render %s %s: %w", tax.Singular, term.Slug, err)
+			}
+			if err := emit(tax.Key+"/"+term.Slug+"/index.html", html); err != nil {
+				return err
+			}
+		}
 	}
 
-	return nil
+	// 404 page, which suggests similar slugs from a generated list
+	if !r.HasNotFound() {
+		return nil
+	}
+	html, err = r.RenderNotFound(site, "/"+slugListFile)
+	if err != nil {
+		return fmt.Errorf("render 404: %w", err)
+	}
+	return emit("404.html", html)
 }
 
-func (b *Builder) generateDiscoveryFiles(site *model.Site) error {
-	if err := b.generateRobotsTxt(site); err != nil {
-		return fmt.Errorf("robots.txt: %w", err)
-	}
-	if err := b.generateSitemap(site); err != nil {
-		return fmt.Errorf("sitemap.xml: %w", err)
+// slugListFile holds the post and page slugs the 404 page suggests from.
+const slugListFile = "slugs.json"
+
+type slugEntry struct {
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+func (b *Builder) generateSlugList(site *model.Site) error {
+	entries := make([]slugEntry, 0, len(site.Posts)+len(site.Pages))
+	for _, post := range site.Posts {
+		entries = append(entries, slugEntry{Slug: post.Slug, Title: post.Title, URL: post.URL})
 	}
-	if err := b.generateLlmsTxt(site); err != nil {
-		return fmt.Errorf("llms.txt: %w", err)
+	for _, page := range site.Pages {
+		entries = append(entries, slugEntry{Slug: page.Slug, Title: page.Title, URL: page.URL})
 	}
-	if err := b.generateRSS(site); err != nil {
-		return fmt.Errorf("rss.xml: %w", err)
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
 	}
-	return nil
+	return writeFile(filepath.Join(b.outputDir, slugListFile), append(data, '\n'))
 }
 
-func (b *Builder) generateRobotsTxt(site *model.Site) error {
-	var buf strings.Builder
-	buf.WriteString("User-agent: *\n")
-	buf.WriteString("Allow: /\n\n")
-	buf.WriteString("Sitemap: " + site.BaseURL + "/sitemap.xml\n")
-	return writeFile(filepath.Join(b.outputDir, "robots.txt"), []byte(buf.String()))
+// discoveryFile is written to the output root. It can be overridden by a
+// text/template named after it in assets/templates (e.g. robots.txt.tmpl),
+// executed with the *model.Site.
+type discoveryFile struct {
+	name  string
+	build func(*model.Site) ([]byte, error)
 }
 
-type sitemapURLSet struct {
-	XMLName xml.Name     `xml:"urlset"`
-	XMLNS   string       `xml:"xmlns,attr"`
-	URLs    []sitemapURL `xml:"url"`
+func (b *Builder) discoveryFiles() []discoveryFile {
+	return []discoveryFile{
+		{"robots.txt", robotsTxt},
+		{"sitemap.xml", b.sitemap},
+		{"llms.txt", llmsTxt},
+		{"rss.xml", rss},
+	}
 }
 
-type sitemapURL struct {
-	Loc     string `xml:"loc"`
-	LastMod string `xml:"lastmod,omitempty"`
+// discoveryFuncs are available to discovery file templates.
+var discoveryFuncs = texttemplate.FuncMap{
+	"xml": func(s string) (string, error) {
+		var buf bytes.Buffer
+		err := xml.EscapeText(&buf, []byte(s))
+		return buf.String(), err
+	},
 }
 
-func (b *Builder) generateSitemap(site *model.Site) error {
-	var urls []sitemapURL
-
-	urls = append(urls, sitemapURL{Loc: site.BaseURL + "/"})
-
-	for _, post := range site.Posts {
-		urls = append(urls, sitemapURL{
-			Loc:     site.BaseURL + post.URL,
-			LastMod: post.Date.Format("2006-01-02"),
-		})
-	}
-
-	for _, page := range site.Pages {
-		urls = append(urls, sitemapURL{Loc: site.BaseURL + page.URL})
+func (b *Builder) generateDiscoveryFiles(site *model.Site) error {
+	for _, f := range b.discoveryFiles() {
+		data, err := b.discoveryFile(f.name, site, f.build)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+		if err := writeFile(filepath.Join(b.outputDir, f.name), data); err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
 	}
+	return nil
+}
 
-	urls = append(urls, sitemapURL{Loc: site.BaseURL + "/tags/"})
-	for _, tag := range site.Tags {
-		urls = append(urls, sitemapURL{Loc: site.BaseURL + tag.URL})
+// discoveryFile renders name from its template override if there is one,
+// and with build otherwise.
+func (b *Builder) discoveryFile(name string, site *model.Site, build func(*model.Site) ([]byte, error)) ([]byte, error) {
+	file := filepath.Join(b.assetsDir, "templates", name+".tmpl")
+	src, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return build(site)
 	}
-
-	urlset := sitemapURLSet{
-		XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
-		URLs:  urls,
+	if err != nil {
+		return nil, err
 	}
 
-	data, err := xml.MarshalIndent(urlset, "", "  ")
+	tmpl, err := texttemplate.New(filepath.Base(file)).Funcs(discoveryFuncs).Parse(string(src))
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("parsing template: %w", err)
 	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, site); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
 
-	out := []byte(xml.Header)
-	out = append(out, data...)
-	out = append(out, '\n')
-	return writeFile(filepath.Join(b.outputDir, "sitemap.xml"), out)
+func robotsTxt(site *model.Site) ([]byte, error) {
+	var buf strings.Builder
+	buf.WriteString("User-agent: *\n")
+	buf.WriteString("Allow: /\n\n")
+	buf.WriteString("Sitemap: " + site.BaseURL + "/sitemap.xml\n")
+	return []byte(buf.String()), nil
 }
 
-func (b *Builder) generateLlmsTxt(site *model.Site) error {
+func llmsTxt(site *model.Site) ([]byte, error) {
 	var buf strings.Builder
 	buf.WriteString("# integralist.co.uk\n\n")
 	buf.WriteString("> A personal blog about emotions and the human experience.\n\n")
-	buf.WriteString("Every page has a companion Markdown file at the same path with an index.md suffix.\n\n")
+	buf.WriteString("Every page has a companion Markdown file at the same path with an index.md suffix.\n")
+	buf.WriteString("The Markdown of every post and page is also available in a single file at " + site.BaseURL + "/llms-full.txt,\n")
+	buf.WriteString("and for the posts with a given tag at " + site.BaseURL + "/tags/<tag>/llms.txt.\n\n")
 
 	buf.WriteString("## Posts\n\n")
 	for _, post := range site.Posts {
@@ -255,7 +610,7 @@ func (b *Builder) generateLlmsTxt(site *model.Site) error {
 		}
 	}
 
-	return writeFile(filepath.Join(b.outputDir, "llms.txt"), []byte(buf.String()))
+	return []byte(buf.String()), nil
 }
 
 type rssChannel struct {
@@ -266,51 +621,90 @@ type rssChannel struct {
 }
 
 type rssFeed struct {
-	XMLName xml.Name   `xml:"rss"`
-	Version string     `xml:"version,attr"`
-	Channel rssChannel `xml:"channel"`
+	XMLName       xml.Name   `xml:"rss"`
+	Version       string     `xml:"version,attr"`
+	AtomNS        string     `xml:"xmlns:atom,attr"`
+	ContentModule string     `xml:"xmlns:content,attr"`
+	Channel       rssChannel `xml:"channel"`
 }
 
+// rssItem carries the post's summary as its description and the full post
+// in content:encoded. RSS has no element for when an item was last updated,
+// so that is taken from the Atom namespace.
 type rssItem struct {
+	Content     string `xml:"content:encoded"`
 	Description string `xml:"description"`
 	GUID        string `xml:"guid"`
 	Link        string `xml:"link"`
 	PubDate     string `xml:"pubDate"`
 	Title       string `xml:"title"`
+	Updated     string `xml:"atom:updated"`
 }
 
-func (b *Builder) generateRSS(site *model.Site) error {
-	items := make([]rssItem, 0, len(site.Posts))
-	for _, post := range site.Posts {
+func rss(site *model.Site) ([]byte, error) {
+	return rssFeedOf(site, "integralist", "/", site.Posts)
+}
+
+// rssFeedOf is an RSS feed of posts, titled title, for the page at path.
+func rssFeedOf(site *model.Site, title, path string, posts []*model.Post) ([]byte, error) {
+	items := make([]rssItem, 0, len(posts))
+	for _, post := range posts {
 		link := site.BaseURL + post.URL
 		items = append(items, rssItem{
-			Description: string(post.Content),
+			Content:     string(post.Content),
+			Description: string(post.Summary),
 			GUID:        link,
 			Link:        link,
 			PubDate:     post.Date.Format("Mon, 02 Jan 2006 15:04:05 -0700"),
 			Title:       post.Title,
+			Updated:     cmp.Or(post.Updated, post.Date).Format(time.RFC3339),
 		})
 	}
 
 	feed := rssFeed{
-		Version: "2.0",
+		Version:       "2.0",
+		AtomNS:        "http://www.w3.org/2005/Atom",
+		ContentModule: "http://purl.org/rss/1.0/modules/content/",
 		Channel: rssChannel{
 			Description: "A personal blog about emotions and the human experience.",
 			Items:       items,
-			Link:        site.BaseURL + "/",
-			Title:       "integralist",
+			Link:        site.BaseURL + path,
+			Title:       title,
 		},
 	}
 
-	data, err := xml.MarshalIndent(feed, "", "  ")
+	return marshalXML(feed)
+}
+
+// generateTermFeeds writes an RSS feed of the posts of each term, such as
+// tags/go/rss.xml.
+func (b *Builder) generateTermFeeds(site *model.Site) error {
+	for _, tax := range site.Taxonomies {
+		for _, term := range tax.Terms {
+			title := "integralist: " + tax.TermTitle(term)
+			dir := filepath.Join(b.outputDir, tax.Key, term.Slug)
+			data, err := rssFeedOf(site, title, term.URL, term.Posts)
+			if err != nil {
+				return fmt.Errorf("%s/%s/rss.xml: %w", tax.Key, term.Slug, err)
+			}
+			if err := writeFile(filepath.Join(dir, "rss.xml"), data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func marshalXML(v any) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	out := []byte(xml.Header)
 	out = append(out, data...)
 	out = append(out, '\n')
-	return writeFile(filepath.Join(b.outputDir, "rss.xml"), out)
+	return out, nil
 }
 
 func writeFile(path string, data []byte) error {
@@ -319,24 +713,3 @@ func writeFile(path string, data []byte) error {
 	}
 	return os.WriteFile(path, data, 0o644)
 }
-
-func copyFile(src, dst string) error {
-	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
-		return err
-	}
-
-	in, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer in.Close()
-
-	out, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, in)
-	return err
-}
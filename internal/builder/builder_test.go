@@ -1,12 +1,19 @@
 package builder_test
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"image/png"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/integralist/integralist.co.uk/internal/builder"
+	"github.com/integralist/integralist.co.uk/internal/card"
+	"github.com/integralist/integralist.co.uk/internal/config"
 )
 
 func setupTestProject(t *testing.T) (contentDir, assetsDir, outputDir string) {
@@ -44,12 +51,8 @@ This is about me.
 
 	// Copy real templates
 	realTemplates := "../../assets/templates"
-	templateDir := filepath.Join(assetsDir, "templates")
-	os.MkdirAll(templateDir, 0o755)
-	entries, _ := os.ReadDir(realTemplates)
-	for _, e := range entries {
-		data, _ := os.ReadFile(filepath.Join(realTemplates, e.Name()))
-		os.WriteFile(filepath.Join(templateDir, e.Name()), data, 0o644)
+	if err := os.CopyFS(filepath.Join(assetsDir, "templates"), os.DirFS(realTemplates)); err != nil {
+		t.Fatal(err)
 	}
 
 	// Create a CSS file
@@ -81,9 +84,9 @@ func TestBuild_CopiesAssets(t *testing.T) {
 		t.Fatalf("Build error: %v", err)
 	}
 
-	cssPath := filepath.Join(outputDir, "assets", "css", "style.css")
-	if _, err := os.Stat(cssPath); os.IsNotExist(err) {
-		t.Error("CSS file not copied to output")
+	matches, _ := filepath.Glob(filepath.Join(outputDir, "assets", "css", "style.*.css"))
+	if len(matches) != 1 {
+		t.Errorf("fingerprinted CSS files = %v, want one", matches)
 	}
 
 	// Templates should NOT be copied
@@ -266,6 +269,65 @@ func TestBuild_GeneratesSitemapXml(t *testing.T) {
 	}
 }
 
+// Verifies that every sitemap URL has a lastmod, with tag pages taking their
+// newest post's, and that posts list their hero and inline images.
+func TestBuild_SitemapLastModAndImages(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	os.WriteFile(filepath.Join(contentDir, "posts", "pics.md"), []byte("---\ntitle: Pics\ndate: 2026-04-01\nupdated: 2026-05-20\ntags: [photos]\nimage: /assets/img/hero.png\n---\n![a](/assets/img/a.png) ![rel](b.png) ![again](/assets/img/hero.png)\n"), 0o644)
+	os.WriteFile(filepath.Join(contentDir, "pages", "about.md"), []byte("---\ntitle: About\nupdated: 2026-03-03\n---\nAbout.\n"), 0o644)
+
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk")
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(outputDir, "sitemap.xml"))
+	sitemap := string(data)
+	if strings.Count(sitemap, "<url>") != strings.Count(sitemap, "<lastmod>") {
+		t.Errorf("not every URL has a lastmod:\n%s", sitemap)
+	}
+	for _, want := range []string{
+		"<loc>https://www.integralist.co.uk/about/</loc>\n    <lastmod>2026-03-03</lastmod>",
+		"<loc>https://www.integralist.co.uk/tags/photos/</loc>\n    <lastmod>2026-05-20</lastmod>",
+		`xmlns:image="http://www.google.com/schemas/sitemap-image/1.1"`,
+		"<lastmod>2026-05-20</lastmod>\n    <image:image>\n      <image:loc>https://www.integralist.co.uk/assets/img/hero.png</image:loc>\n    </image:image>\n    <image:image>\n      <image:loc>https://www.integralist.co.uk/assets/img/a.png</image:loc>\n    </image:image>\n    <image:image>\n      <image:loc>https://www.integralist.co.uk/posts/pics/b.png</image:loc>\n    </image:image>\n  </url>",
+	} {
+		if !strings.Contains(sitemap, want) {
+			t.Errorf("sitemap missing %q", want)
+		}
+	}
+}
+
+// Verifies that a sitemap index is written when URLs exceed the per-file limit.
+func TestBuild_SitemapIndex(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	cfg := config.Default()
+	cfg.Sitemap.MaxURLs = 3
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk", builder.WithConfig(cfg))
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	// Home, one post, one page, the tags index and two tags: six URLs.
+	data, _ := os.ReadFile(filepath.Join(outputDir, "sitemap.xml"))
+	index := string(data)
+	if !strings.Contains(index, "<sitemapindex") || strings.Count(index, "<sitemap>") != 2 {
+		t.Fatalf("sitemap.xml = %s, want an index of two sitemaps", index)
+	}
+	for _, name := range []string{"sitemap-1.xml", "sitemap-2.xml"} {
+		if !strings.Contains(index, "<loc>https://www.integralist.co.uk/"+name+"</loc>") {
+			t.Errorf("index missing %s", name)
+		}
+		part, err := os.ReadFile(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Fatalf("%s not generated: %v", name, err)
+		}
+		if n := strings.Count(string(part), "<url>"); n != 3 {
+			t.Errorf("%s has %d URLs, want 3", name, n)
+		}
+	}
+}
+
 // Verifies that llms.txt is generated with site description and content listing.
 func TestBuild_GeneratesLlmsTxt(t *testing.T) {
 	contentDir, assetsDir, outputDir := setupTestProject(t)
@@ -332,8 +394,79 @@ func TestBuild_GeneratesRSSFeed(t *testing.T) {
 	if !strings.Contains(content, "<description>") {
 		t.Error("rss.xml missing description element")
 	}
-	if !strings.Contains(content, "This is my first post") {
-		t.Error("rss.xml missing full post content in description")
+	if !strings.Contains(content, "<description>&lt;p&gt;This is my first post.&lt;/p&gt;</description>") {
+		t.Error("rss.xml missing post summary in description")
+	}
+	if !strings.Contains(content, `xmlns:content="http://purl.org/rss/1.0/modules/content/"`) ||
+		!strings.Contains(content, "<content:encoded>&lt;h1") {
+		t.Error("rss.xml missing full post content in content:encoded")
+	}
+}
+
+// Verifies that rss.xml records when each post was last updated.
+func TestBuild_RSSUpdated(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	os.WriteFile(filepath.Join(contentDir, "posts", "edited.md"), []byte("---\ntitle: Edited\ndate: 2026-04-01\nupdated: 2026-05-20\n---\nEdited.\n"), 0o644)
+
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk")
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "rss.xml"))
+	if err != nil {
+		t.Fatalf("rss.xml not generated: %v", err)
+	}
+	feed := string(data)
+	for _, want := range []string{
+		`xmlns:atom="http://www.w3.org/2005/Atom"`,
+		"<atom:updated>2026-05-20T00:00:00Z</atom:updated>",
+		"<atom:updated>2026-04-12T00:00:00Z</atom:updated>",
+	} {
+		if !strings.Contains(feed, want) {
+			t.Errorf("rss.xml missing %q", want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "atom.xml")); !os.IsNotExist(err) {
+		t.Error("atom.xml written, want only rss.xml")
+	}
+}
+
+// Verifies that posts without an image get a generated card as their og:image.
+func TestBuild_GeneratesCards(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	os.WriteFile(filepath.Join(contentDir, "posts", "pictured.md"), []byte("---\ntitle: Pictured\ndate: 2026-04-01\nimage: /assets/img/hero.jpg\n---\nBody.\n"), 0o644)
+	cfg := config.Default()
+	cfg.Cards.Enabled = true
+	cfg.Cards.CacheDir = t.TempDir()
+
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk", builder.WithConfig(cfg))
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "posts", "hello-world", "og.png"))
+	if err != nil {
+		t.Fatalf("card not generated: %v", err)
+	}
+	if img, err := png.Decode(bytes.NewReader(data)); err != nil || img.Bounds().Dx() != card.Width {
+		t.Errorf("card is not a %dpx wide PNG: %v", card.Width, err)
+	}
+	html, _ := os.ReadFile(filepath.Join(outputDir, "posts", "hello-world", "index.html"))
+	for _, want := range []string{
+		`<meta property="og:image" content="https://www.integralist.co.uk/posts/hello-world/og.png">`,
+		`<meta name="twitter:card" content="summary_large_image">`,
+	} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("post page missing %q", want)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(outputDir, "posts", "pictured", "og.png")); !os.IsNotExist(err) {
+		t.Errorf("post with an image got a card (stat error %v)", err)
+	}
+	if cached, _ := filepath.Glob(filepath.Join(cfg.Cards.CacheDir, "*.png")); len(cached) != 1 {
+		t.Errorf("got %d cached cards, want 1", len(cached))
 	}
 }
 
@@ -380,3 +513,553 @@ func TestBuild_MarkdownAlternateLinkInHTML(t *testing.T) {
 		t.Error("post HTML missing markdown type")
 	}
 }
+
+// Verifies that nested pages render at their nested URL.
+func TestBuild_GeneratesNestedPageHTML(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	os.MkdirAll(filepath.Join(contentDir, "pages", "projects"), 0o755)
+	os.WriteFile(filepath.Join(contentDir, "pages", "projects", "_index.md"), []byte(`---
+title: "Projects"
+---
+Things I've built.
+`), 0o644)
+	os.WriteFile(filepath.Join(contentDir, "pages", "projects", "foo.md"), []byte(`---
+title: "Foo"
+---
+Foo project.
+`), 0o644)
+
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk")
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "projects", "foo", "index.html"))
+	if err != nil {
+		t.Fatalf("nested page HTML not generated: %v", err)
+	}
+	html := string(data)
+	if !strings.Contains(html, `class="breadcrumbs"`) {
+		t.Error("nested page missing breadcrumbs")
+	}
+	if !strings.Contains(html, `href="/projects/"`) {
+		t.Error("nested page breadcrumbs missing section link")
+	}
+
+	data, err = os.ReadFile(filepath.Join(outputDir, "projects", "index.html"))
+	if err != nil {
+		t.Fatalf("section page HTML not generated: %v", err)
+	}
+	if !strings.Contains(string(data), `href="/projects/foo/"`) {
+		t.Error("section page missing child page link")
+	}
+}
+
+// Verifies that build-time diagrams are inlined as SVG and that posts asking
+// for mermaid no longer load it.
+func TestBuild_DiagramsSkipMermaidBundle(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	os.WriteFile(filepath.Join(contentDir, "posts", "flow.md"), []byte("---\ntitle: Flow\ndate: 2026-04-13\njs: [mermaid]\n---\n```mermaid\ngraph TD; A-->B\n```\n"), 0o644)
+
+	cfg := config.Default()
+	cfg.Markdown.Diagrams.Enabled = true
+	cfg.Markdown.Diagrams.Command = []string{"sh", "-c", "printf '<svg>'; cat; printf '</svg>'"}
+	cfg.Markdown.Diagrams.CacheDir = ""
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk", builder.WithConfig(cfg))
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "posts", "flow", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+	if !strings.Contains(html, `<div class="diagram diagram-mermaid"><svg>`) {
+		t.Error("post missing inline diagram SVG")
+	}
+	if strings.Contains(html, "mermaid.initialize") || strings.Contains(html, "cdn.jsdelivr.net") {
+		t.Error("post still loads mermaid from the CDN")
+	}
+}
+
+func TestBuild_AssetBundles(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	os.MkdirAll(filepath.Join(assetsDir, "js"), 0o755)
+	os.WriteFile(filepath.Join(assetsDir, "js", "prism.js"), []byte("/* prism */"), 0o644)
+	os.WriteFile(filepath.Join(assetsDir, "css", "prism.css"), []byte("/* prism */"), 0o644)
+	os.WriteFile(filepath.Join(contentDir, "posts", "diagrams.md"), []byte(`---
+title: "Diagrams"
+date: 2026-04-13
+js: [mermaid, prism]
+css: [prism]
+---
+Body.
+`), 0o644)
+
+	cfg := config.Default()
+	cfg.Bundles = map[string]config.Bundle{
+		"prism": {Scripts: []string{"js/prism.js"}, Styles: []string{"css/prism.css"}},
+	}
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk", builder.WithConfig(cfg))
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	scripts, _ := filepath.Glob(filepath.Join(outputDir, "assets", "js", "prism.*.js"))
+	styles, _ := filepath.Glob(filepath.Join(outputDir, "assets", "css", "prism.*.css"))
+	if len(scripts) != 1 || len(styles) != 1 {
+		t.Fatalf("fingerprinted bundle files = %v %v, want one of each", scripts, styles)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "posts", "diagrams", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+	for _, want := range []string{
+		`<script src="/assets/js/` + filepath.Base(scripts[0]) + `" defer></script>`,
+		`<link rel="stylesheet" href="/assets/css/` + filepath.Base(styles[0]) + `">`,
+		"mermaid.initialize",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("post HTML missing %q", want)
+		}
+	}
+
+	data, err = os.ReadFile(filepath.Join(outputDir, "posts", "hello-world", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "mermaid") || strings.Contains(string(data), "prism") {
+		t.Error("post without bundles includes bundle assets")
+	}
+}
+
+func TestBuild_UnknownBundleFails(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	os.WriteFile(filepath.Join(contentDir, "posts", "typo.md"), []byte(`---
+title: "Typo"
+date: 2026-04-13
+js: [mermiad]
+---
+Body.
+`), 0o644)
+
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk")
+	err := b.Build()
+	if err == nil || !strings.Contains(err.Error(), `typo.md: unknown js bundle "mermiad"`) {
+		t.Errorf("error = %v, want unknown bundle error naming the file", err)
+	}
+}
+
+func TestBuild_FingerprintsAssets(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk")
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "assets", "manifest.json"))
+	if err != nil {
+		t.Fatalf("manifest.json not generated: %v", err)
+	}
+	var manifest map[string]string
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("manifest.json: %v", err)
+	}
+	css := manifest["css/style.css"]
+	if !strings.HasPrefix(css, "/assets/css/style.") || css == "/assets/css/style.css" {
+		t.Fatalf("manifest css/style.css = %q, want fingerprinted URL", css)
+	}
+
+	html, _ := os.ReadFile(filepath.Join(outputDir, "index.html"))
+	if !strings.Contains(string(html), `href="`+css+`"`) {
+		t.Errorf("homepage does not link %s", css)
+	}
+
+	headers, err := os.ReadFile(filepath.Join(outputDir, "_headers"))
+	if err != nil {
+		t.Fatalf("_headers not generated: %v", err)
+	}
+	want := css + "\n  Cache-Control: public, max-age=31536000, immutable\n"
+	if !strings.Contains(string(headers), want) {
+		t.Errorf("_headers = %q, want immutable caching for %s", headers, css)
+	}
+}
+
+func TestBuild_Minify(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	os.WriteFile(filepath.Join(contentDir, "posts", "code.md"), []byte("---\ntitle: \"Code\"\ndate: 2026-04-13\n---\n```go\nfunc main() {\n\tgo   run()\n}\n```\n"), 0o644)
+
+	cfg := config.Default()
+	cfg.Minify = true
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk", builder.WithConfig(cfg))
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "posts", "code", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+	if strings.Contains(html, "\n    ") {
+		t.Error("HTML indentation not removed")
+	}
+	if !strings.Contains(html, "func main() {\n\tgo   run()\n}") {
+		t.Error("code block whitespace not preserved")
+	}
+
+	css, _ := filepath.Glob(filepath.Join(outputDir, "assets", "css", "style.*.css"))
+	data, _ = os.ReadFile(css[0])
+	if string(data) != "body{margin:0}" {
+		t.Errorf("CSS = %q, want minified", data)
+	}
+	sum := sha256.Sum256(data)
+	if want := "style." + hex.EncodeToString(sum[:4]) + ".css"; filepath.Base(css[0]) != want {
+		t.Errorf("CSS file = %s, want %s, named for the minified content", filepath.Base(css[0]), want)
+	}
+}
+
+func TestBuild_CompanionFrontMatter(t *testing.T) {
+	tests := map[string]struct {
+		mode    string
+		want    []string
+		notWant []string
+	}{
+		"keep":   {"keep", []string{"---\ntitle: \"Hello World\""}, nil},
+		"strip":  {"strip", []string{"# Hello\n\nThis is my first post."}, []string{"---", "title:"}},
+		"header": {"header", []string{"# Hello World\n\n> My first post\n\n- Published: 2026-04-12\n- Tags: go, ssg\n- URL: https://www.integralist.co.uk/posts/hello-world/\n\n# Hello"}, []string{"---"}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			contentDir, assetsDir, outputDir := setupTestProject(t)
+			cfg := config.Default()
+			cfg.Companions.FrontMatter = tc.mode
+			b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk", builder.WithConfig(cfg))
+			if err := b.Build(); err != nil {
+				t.Fatalf("Build error: %v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(outputDir, "posts", "hello-world", "index.md"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			md := string(data)
+			for _, want := range tc.want {
+				if !strings.Contains(md, want) {
+					t.Errorf("index.md = %q, want %q", md, want)
+				}
+			}
+			for _, notWant := range tc.notWant {
+				if strings.Contains(md, notWant) {
+					t.Errorf("index.md = %q, should not contain %q", md, notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestBuild_HeadersAdvertiseCompanions(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk")
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "_headers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "/posts/hello-world/\n  Link: </posts/hello-world/index.md>; rel=\"alternate\"; type=\"text/markdown\"\n  Vary: Accept\n"
+	if !strings.Contains(string(data), want) {
+		t.Errorf("_headers = %q, want %q", data, want)
+	}
+}
+
+// Verifies that the 404 page and the slug list it suggests from are generated.
+func TestBuild_GeneratesNotFoundPage(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk")
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "404.html"))
+	if err != nil {
+		t.Fatalf("404.html not generated: %v", err)
+	}
+	html := string(data)
+	for _, want := range []string{"Page not found", `<meta name="robots" content="noindex">`, `href="/about/"`, "/slugs.json"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("404.html missing %q", want)
+		}
+	}
+
+	data, err = os.ReadFile(filepath.Join(outputDir, "slugs.json"))
+	if err != nil {
+		t.Fatalf("slugs.json not generated: %v", err)
+	}
+	var entries []struct{ Slug, Title, URL string }
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Slug != "hello-world" || entries[0].URL != "/posts/hello-world/" || entries[1].Slug != "about" {
+		t.Errorf("slugs.json = %+v, want the post then the page", entries)
+	}
+}
+
+// Verifies that a template set without 404.html builds without a 404 page or
+// slug list.
+func TestBuild_WithoutNotFoundTemplate(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	if err := os.Remove(filepath.Join(assetsDir, "templates", "404.html")); err != nil {
+		t.Fatal(err)
+	}
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk")
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	for _, name := range []string{"404.html", "slugs.json"} {
+		if _, err := os.Stat(filepath.Join(outputDir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s generated without a 404 template", name)
+		}
+	}
+}
+
+func TestBuild_DiscoveryTemplateOverrides(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	templateDir := filepath.Join(assetsDir, "templates")
+	os.WriteFile(filepath.Join(templateDir, "robots.txt.tmpl"), []byte("User-agent: *\nDisallow: /drafts/\n\nSitemap: {{.BaseURL}}/sitemap.xml\n"), 0o644)
+	os.WriteFile(filepath.Join(templateDir, "llms.txt.tmpl"), []byte("# Custom\n{{range .Posts}}\n- [{{.Title}}]({{$.BaseURL}}{{.URL}}index.md){{end}}\n\n## Extra\n"), 0o644)
+
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk")
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	robots, _ := os.ReadFile(filepath.Join(outputDir, "robots.txt"))
+	if string(robots) != "User-agent: *\nDisallow: /drafts/\n\nSitemap: https://www.integralist.co.uk/sitemap.xml\n" {
+		t.Errorf("robots.txt = %q, want template output", robots)
+	}
+	llms, _ := os.ReadFile(filepath.Join(outputDir, "llms.txt"))
+	if string(llms) != "# Custom\n\n- [Hello World](https://www.integralist.co.uk/posts/hello-world/index.md)\n\n## Extra\n" {
+		t.Errorf("llms.txt = %q, want template output", llms)
+	}
+
+	// Files without an override keep the built-in output.
+	sitemap, _ := os.ReadFile(filepath.Join(outputDir, "sitemap.xml"))
+	if !strings.Contains(string(sitemap), "<urlset") {
+		t.Errorf("sitemap.xml = %q, want built-in output", sitemap)
+	}
+}
+
+func TestBuild_DiscoveryTemplateError(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	os.WriteFile(filepath.Join(assetsDir, "templates", "robots.txt.tmpl"), []byte("{{.NoSuchField}}"), 0o644)
+
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk")
+	err := b.Build()
+	if err == nil || !strings.Contains(err.Error(), "robots.txt: executing template") {
+		t.Errorf("error = %v, want robots.txt template error", err)
+	}
+}
+
+func TestBuild_GeneratesLlmsFull(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	os.WriteFile(filepath.Join(contentDir, "posts", "links.md"), []byte("---\ntitle: \"Links\"\ndate: 2026-04-13\ntags: [go]\n---\nSee [hello](/posts/hello-world/), [below](#below), [sibling](../hello-world/) and ![img](/assets/img/a.png).\n\n[ref]: /tags/go/\n\n```md\n[untouched](/in/code/)\n```\n\nWrite `[span](/in/span/)` or ``[`double`](/in/double/)``, then [after](/after/).\n\n~~~~md\n```\n[nested](/in/nested/)\n~~~~\n"), 0o644)
+
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk")
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "llms-full.txt"))
+	if err != nil {
+		t.Fatalf("llms-full.txt not generated: %v", err)
+	}
+	full := string(data)
+	for _, want := range []string{
+		"# Links\n\n- Date: 2026-04-13\n- URL: https://www.integralist.co.uk/posts/links/\n\n",
+		"[hello](https://www.integralist.co.uk/posts/hello-world/)",
+		"[below](https://www.integralist.co.uk/posts/links/#below)",
+		"[sibling](https://www.integralist.co.uk/posts/hello-world/)",
+		"![img](https://www.integralist.co.uk/assets/img/a.png)",
+		"[ref]: https://www.integralist.co.uk/tags/go/",
+		"[untouched](/in/code/)",
+		"`[span](/in/span/)`",
+		"``[`double`](/in/double/)``",
+		"[after](https://www.integralist.co.uk/after/)",
+		"[nested](/in/nested/)",
+		"# About\n\n- URL: https://www.integralist.co.uk/about/\n\n# About\n\nThis is about me.",
+	} {
+		if !strings.Contains(full, want) {
+			t.Errorf("llms-full.txt missing %q", want)
+		}
+	}
+	if strings.Contains(full, "title: ") {
+		t.Error("llms-full.txt contains front matter")
+	}
+	// Posts are newest first, as in site.Posts, followed by pages.
+	if links, hello, about := strings.Index(full, "# Links"), strings.Index(full, "# Hello World"), strings.Index(full, "# About"); !(links < hello && hello < about) {
+		t.Errorf("entries out of order: Links@%d Hello@%d About@%d", links, hello, about)
+	}
+
+	data, err = os.ReadFile(filepath.Join(outputDir, "tags", "ssg", "llms.txt"))
+	if err != nil {
+		t.Fatalf("tag llms.txt not generated: %v", err)
+	}
+	tag := string(data)
+	if !strings.Contains(tag, "posts tagged ssg") || !strings.Contains(tag, "# Hello World") || strings.Contains(tag, "# Links") {
+		t.Errorf("tags/ssg/llms.txt = %q, want only the ssg post", tag)
+	}
+}
+
+// Verifies that entries past the size cap are left out, and that the note
+// saying so fits within the cap too.
+func TestBuild_LlmsFullSizeCap(t *testing.T) {
+	for _, tc := range []struct {
+		maxBytes int
+		post     bool
+		note     string
+	}{
+		{maxBytes: 360, post: true, note: "[Truncated: 1 more entries omitted."},
+		// The post fits on its own, but not with room for the note after it.
+		{maxBytes: 320, post: false, note: "[Truncated: 2 more entries omitted."},
+	} {
+		contentDir, assetsDir, outputDir := setupTestProject(t)
+		os.WriteFile(filepath.Join(contentDir, "pages", "about.md"), []byte("---\ntitle: \"About\"\n---\n"+strings.Repeat("This is about me.\n", 20)), 0o644)
+		cfg := config.Default()
+		cfg.LLMs.MaxBytes = tc.maxBytes
+		b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk", builder.WithConfig(cfg))
+		if err := b.Build(); err != nil {
+			t.Fatalf("Build error: %v", err)
+		}
+
+		data, _ := os.ReadFile(filepath.Join(outputDir, "llms-full.txt"))
+		full := string(data)
+		if strings.Contains(full, "# Hello World") != tc.post || strings.Contains(full, "This is about me.") {
+			t.Errorf("max %d: llms-full.txt = %q, want post %t and not the page", tc.maxBytes, full, tc.post)
+		}
+		if !strings.Contains(full, tc.note) {
+			t.Errorf("max %d: llms-full.txt = %q, want %q", tc.maxBytes, full, tc.note)
+		}
+		if len(full) > tc.maxBytes {
+			t.Errorf("llms-full.txt is %d bytes, want at most %d", len(full), tc.maxBytes)
+		}
+	}
+}
+
+func TestPreview_RendersWithoutWriting(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk")
+
+	site, sizes, err := b.Preview()
+	if err != nil {
+		t.Fatalf("Preview error: %v", err)
+	}
+	if len(site.Posts) != 1 {
+		t.Errorf("got %d posts, want 1", len(site.Posts))
+	}
+	for _, url := range []string{"/", "/posts/hello-world/", "/about/", "/tags/", "/tags/go/", "/404.html"} {
+		if sizes[url] == 0 {
+			t.Errorf("no size for %s in %v", url, sizes)
+		}
+	}
+	if _, err := os.Stat(outputDir); !os.IsNotExist(err) {
+		t.Errorf("output directory exists after Preview (err %v)", err)
+	}
+}
+
+func TestBuild_TagAliasRedirects(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	os.MkdirAll(filepath.Join(contentDir, "tags"), 0o755)
+	os.WriteFile(filepath.Join(contentDir, "tags", "go.md"), []byte("---\nname: Go\naliases: [golang, Go Lang]\n---\nAll about Go.\n"), 0o644)
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk")
+
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "_redirects"))
+	if err != nil {
+		t.Fatalf("_redirects not generated: %v", err)
+	}
+	want := "/tags/golang/ /tags/go/ 301\n/tags/go-lang/ /tags/go/ 301\n"
+	if string(data) != want {
+		t.Errorf("_redirects = %q, want %q", data, want)
+	}
+
+	html, err := os.ReadFile(filepath.Join(outputDir, "tags", "go", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(html), "All about Go.") {
+		t.Error("tag page missing its description")
+	}
+}
+
+func TestBuild_NoRedirectsWithoutAliases(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk")
+
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "_redirects")); !os.IsNotExist(err) {
+		t.Errorf("_redirects written without aliases (err %v)", err)
+	}
+}
+
+func TestBuild_Taxonomies(t *testing.T) {
+	contentDir, assetsDir, outputDir := setupTestProject(t)
+	os.WriteFile(filepath.Join(contentDir, "posts", "part-one.md"), []byte("---\ntitle: Part One\ndate: 2026-04-13\nseries: Go Concurrency\n---\nOne.\n"), 0o644)
+	os.MkdirAll(filepath.Join(contentDir, "series"), 0o755)
+	os.WriteFile(filepath.Join(contentDir, "series", "go-concurrency.md"), []byte("---\naliases: [goroutines]\n---\n"), 0o644)
+
+	cfg := config.Default()
+	cfg.Taxonomies = []config.Taxonomy{{Key: "series", Singular: "series", Title: "Series"}}
+	b := builder.New(contentDir, assetsDir, outputDir, "https://www.integralist.co.uk", builder.WithConfig(cfg))
+	if err := b.Build(); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	for _, file := range []string{
+		"series/index.html",
+		"series/go-concurrency/index.html",
+		"series/go-concurrency/rss.xml",
+		"tags/go/rss.xml",
+	} {
+		if _, err := os.Stat(filepath.Join(outputDir, file)); err != nil {
+			t.Errorf("%s not generated: %v", file, err)
+		}
+	}
+
+	rss, err := os.ReadFile(filepath.Join(outputDir, "series", "go-concurrency", "rss.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(rss), "<title>Part One</title>") || strings.Contains(string(rss), "<title>Hello World</title>") {
+		t.Errorf("series feed should list only Part One:\n%s", rss)
+	}
+
+	sitemap, err := os.ReadFile(filepath.Join(outputDir, "sitemap.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, loc := range []string{"/series/", "/series/go-concurrency/"} {
+		if !strings.Contains(string(sitemap), "<loc>https://www.integralist.co.uk"+loc+"</loc>") {
+			t.Errorf("sitemap missing %s", loc)
+		}
+	}
+
+	redirects, err := os.ReadFile(filepath.Join(outputDir, "_redirects"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/series/goroutines/ /series/go-concurrency/ 301\n"; string(redirects) != want {
+		t.Errorf("_redirects = %q, want %q", redirects, want)
+	}
+}
@@ -0,0 +1,67 @@
+package builder
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/integralist/integralist.co.uk/internal/card"
+	"github.com/integralist/integralist.co.uk/internal/model"
+)
+
+// cardFile is the name of a post's social card within its output directory.
+const cardFile = "og.png"
+
+// generateCards draws a social card for each post without an image and sets
+// its CardImage, so the renderer can use it for og:image.
+func (b *Builder) generateCards(site *model.Site) error {
+	cfg := b.config.Cards
+
+	var logo []byte
+	if cfg.Logo != "" {
+		var err error
+		if logo, err = os.ReadFile(filepath.Join(b.assetsDir, cfg.Logo)); err != nil {
+			return fmt.Errorf("reading logo: %w", err)
+		}
+	}
+
+	colors := make(map[string]string, len(site.Tags))
+	for _, t := range site.Tags {
+		colors[t.Name] = t.Color
+	}
+
+	name := b.baseURL
+	if u, err := url.Parse(b.baseURL); err == nil && u.Host != "" {
+		name = strings.TrimPrefix(u.Host, "www.")
+	}
+
+	cache := card.Cache{Dir: cfg.CacheDir}
+	for _, post := range site.Posts {
+		if post.Image != "" {
+			continue
+		}
+
+		c := card.Card{Title: post.Title, Date: post.Date, Site: name, Logo: logo}
+		for _, t := range post.Tags {
+			c.Tags = append(c.Tags, card.Tag{Name: t, Color: colors[t]})
+		}
+
+		var data []byte
+		var err error
+		if cache.Dir != "" {
+			data, err = cache.Render(c)
+		} else {
+			data, err = card.Render(c)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", post.Slug, err)
+		}
+		if err := writeFile(filepath.Join(b.outputDir, "posts", post.Slug, cardFile), data); err != nil {
+			return err
+		}
+		post.CardImage = post.URL + cardFile
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/integralist/integralist.co.uk/internal/parser"
+)
+
+// companionHeader is the metadata shown in place of front matter when
+// companions use the "header" mode.
+type companionHeader struct {
+	Date        time.Time
+	Description string
+	Tags        []string
+	Title       string
+	URL         string
+}
+
+// companion returns the index.md written next to a page's HTML. mode is
+// "keep" (the source as written), "strip" (front matter removed) or
+// "header" (front matter replaced with a readable Markdown block).
+func companion(mode string, source []byte, h companionHeader) ([]byte, error) {
+	if mode == "keep" {
+		return source, nil
+	}
+
+	_, body, err := parser.ParseFrontMatter(source)
+	if err != nil {
+		return nil, err
+	}
+	if mode == "strip" {
+		return append(body, '\n'), nil
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "# %s\n\n", h.Title)
+	if h.Description != "" {
+		fmt.Fprintf(&buf, "> %s\n\n", h.Description)
+	}
+	if !h.Date.IsZero() {
+		fmt.Fprintf(&buf, "- Published: %s\n", h.Date.Format("2006-01-02"))
+	}
+	if len(h.Tags) > 0 {
+		fmt.Fprintf(&buf, "- Tags: %s\n", strings.Join(h.Tags, ", "))
+	}
+	fmt.Fprintf(&buf, "- URL: %s\n\n", h.URL)
+	buf.Write(body)
+	buf.WriteString("\n")
+	return []byte(buf.String()), nil
+}
@@ -0,0 +1,184 @@
+package builder
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/integralist/integralist.co.uk/internal/model"
+	"github.com/integralist/integralist.co.uk/internal/parser"
+)
+
+var (
+	// inlineLink matches the destination of an inline link or image:
+	// [text](dest) or ![alt](dest "title").
+	inlineLink = regexp.MustCompile(`(\]\()([^)\s]+)`)
+	// referenceLink matches a link reference definition: [label]: dest
+	referenceLink = regexp.MustCompile(`(?m)^( {0,3}\[[^\]]+\]:[ \t]*)(\S+)`)
+	fence         = regexp.MustCompile("^\\s*(`{3,}|~{3,})")
+	// backticks matches the runs of backticks that open and close inline
+	// code spans.
+	backticks = regexp.MustCompile("`+")
+)
+
+// llmsEntry is one post or page in an llms-full.txt file.
+type llmsEntry struct {
+	Date   time.Time
+	Source []byte
+	Title  string
+	URL    string
+}
+
+// generateLlmsFull writes llms-full.txt, with the Markdown of every post and
+// page, and a tags/<slug>/llms.txt for each tag with the Markdown of its
+// posts.
+func (b *Builder) generateLlmsFull(site *model.Site) error {
+	var entries []llmsEntry
+	for _, post := range site.Posts {
+		entries = append(entries, llmsEntry{Date: post.Date, Source: post.SourceMD, Title: post.Title, URL: post.URL})
+	}
+	for _, page := range site.Pages {
+		entries = append(entries, llmsEntry{Source: page.SourceMD, Title: page.Title, URL: page.URL})
+	}
+	data, err := b.llmsFull(site, "integralist.co.uk", entries)
+	if err != nil {
+		return fmt.Errorf("llms-full.txt: %w", err)
+	}
+	if err := writeFile(filepath.Join(b.outputDir, "llms-full.txt"), data); err != nil {
+		return err
+	}
+
+	for _, tag := range site.Tags {
+		entries := make([]llmsEntry, 0, len(tag.Posts))
+		for _, post := range tag.Posts {
+			entries = append(entries, llmsEntry{Date: post.Date, Source: post.SourceMD, Title: post.Title, URL: post.URL})
+		}
+		data, err := b.llmsFull(site, "integralist.co.uk: posts tagged "+tag.Name, entries)
+		if err != nil {
+			return fmt.Errorf("tags/%s/llms.txt: %w", tag.Slug, err)
+		}
+		if err := writeFile(filepath.Join(b.outputDir, "tags", tag.Slug, "llms.txt"), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// llmsFull concatenates entries under a title, each with its front matter
+// replaced by a header and its links made absolute. Once the configured
+// size cap is reached, the remaining entries are left out and a note says
+// how many.
+func (b *Builder) llmsFull(site *model.Site, title string, entries []llmsEntry) ([]byte, error) {
+	maxBytes := b.config.LLMs.MaxBytes
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "# %s\n\n", title)
+	fmt.Fprintf(&buf, "> The full Markdown of each entry below. An index is at %s/llms.txt.\n", site.BaseURL)
+
+	truncated := func(omitted int) string {
+		return fmt.Sprintf("\n---\n\n[Truncated: %d more entries omitted. See %s/llms.txt for links to each.]\n", omitted, site.BaseURL)
+	}
+
+	for i, e := range entries {
+		entry, err := llmsFullEntry(site.BaseURL, e)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.URL, err)
+		}
+		// Every entry but the last leaves room for the note, so adding it
+		// later never takes the file over the cap.
+		reserve := 0
+		if i < len(entries)-1 {
+			reserve = len(truncated(len(entries) - i - 1))
+		}
+		if maxBytes > 0 && buf.Len()+len(entry)+reserve > maxBytes {
+			buf.WriteString(truncated(len(entries) - i))
+			break
+		}
+		buf.WriteString(entry)
+	}
+	return []byte(buf.String()), nil
+}
+
+func llmsFullEntry(baseURL string, e llmsEntry) (string, error) {
+	_, body, err := parser.ParseFrontMatter(e.Source)
+	if err != nil {
+		return "", err
+	}
+	pageURL, err := url.Parse(baseURL + e.URL)
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "\n---\n\n# %s\n\n", e.Title)
+	if !e.Date.IsZero() {
+		fmt.Fprintf(&buf, "- Date: %s\n", e.Date.Format("2006-01-02"))
+	}
+	fmt.Fprintf(&buf, "- URL: %s\n\n", pageURL)
+	buf.Write(absoluteLinks(body, pageURL))
+	buf.WriteString("\n")
+	return buf.String(), nil
+}
+
+// absoluteLinks rewrites the destinations of Markdown links and images in md
+// to absolute URLs, resolved against base. Links inside fenced code blocks
+// and inline code spans are left alone.
+func absoluteLinks(md []byte, base *url.URL) []byte {
+	resolve := func(re *regexp.Regexp, text string) string {
+		return re.ReplaceAllStringFunc(text, func(m string) string {
+			sub := re.FindStringSubmatch(m)
+			dest, err := url.Parse(sub[2])
+			if err != nil {
+				return m
+			}
+			return sub[1] + base.ResolveReference(dest).String()
+		})
+	}
+
+	lines := strings.SplitAfter(string(md), "\n")
+	open := "" // the fence that opened the current code block
+	for i, line := range lines {
+		if open != "" {
+			if s := strings.TrimSpace(line); len(s) >= len(open) && strings.Trim(s, open[:1]) == "" {
+				open = ""
+			}
+			continue
+		}
+		if m := fence.FindStringSubmatch(line); m != nil {
+			open = m[1]
+			continue
+		}
+		line = resolve(referenceLink, line)
+		lines[i] = outsideCodeSpans(line, func(text string) string {
+			return resolve(inlineLink, text)
+		})
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+// outsideCodeSpans applies fn to the parts of line that are not inside an
+// inline code span. A span opens with a run of backticks and closes with the
+// next run of the same length; an unclosed run is literal text.
+func outsideCodeSpans(line string, fn func(string) string) string {
+	var out strings.Builder
+	start := 0 // start of the text not yet passed to fn
+	runs := backticks.FindAllStringIndex(line, -1)
+	for i := 0; i < len(runs); i++ {
+		open := runs[i]
+		for j := i + 1; j < len(runs); j++ {
+			if runs[j][1]-runs[j][0] != open[1]-open[0] {
+				continue
+			}
+			out.WriteString(fn(line[start:open[0]]))
+			out.WriteString(line[open[0]:runs[j][1]])
+			start = runs[j][1]
+			i = j
+			break
+		}
+	}
+	out.WriteString(fn(line[start:]))
+	return out.String()
+}
@@ -0,0 +1,150 @@
+package builder
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/integralist/integralist.co.uk/internal/model"
+)
+
+const (
+	sitemapNS      = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	sitemapImageNS = "http://www.google.com/schemas/sitemap-image/1.1"
+)
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Image   string       `xml:"xmlns:image,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string         `xml:"loc"`
+	LastMod string         `xml:"lastmod,omitempty"`
+	Images  []sitemapImage `xml:"image:image"`
+}
+
+type sitemapImage struct {
+	Loc string `xml:"image:loc"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	XMLNS    string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemap returns sitemap.xml. When the site has more URLs than fit in one
+// file, it writes them to sitemap-1.xml, sitemap-2.xml, ... and returns an
+// index of those files instead.
+func (b *Builder) sitemap(site *model.Site) ([]byte, error) {
+	urls := sitemapURLs(site)
+	limit := b.config.Sitemap.MaxURLs
+	if len(urls) <= limit {
+		return marshalXML(sitemapURLSet{XMLNS: sitemapNS, Image: sitemapImageNS, URLs: urls})
+	}
+
+	index := sitemapIndex{XMLNS: sitemapNS}
+	for i := 0; i*limit < len(urls); i++ {
+		part := urls[i*limit : min((i+1)*limit, len(urls))]
+		data, err := marshalXML(sitemapURLSet{XMLNS: sitemapNS, Image: sitemapImageNS, URLs: part})
+		if err != nil {
+			return nil, err
+		}
+		name := fmt.Sprintf("sitemap-%d.xml", i+1)
+		if err := writeFile(filepath.Join(b.outputDir, name), data); err != nil {
+			return nil, err
+		}
+
+		var lastMod string
+		for _, u := range part {
+			lastMod = max(lastMod, u.LastMod)
+		}
+		index.Sitemaps = append(index.Sitemaps, sitemapEntry{Loc: site.BaseURL + "/" + name, LastMod: lastMod})
+	}
+	return marshalXML(index)
+}
+
+func sitemapURLs(site *model.Site) []sitemapURL {
+	var urls []sitemapURL
+
+	urls = append(urls, sitemapURL{Loc: site.BaseURL + "/", LastMod: sitemapDate(site.LastMod())})
+
+	for _, post := range site.Posts {
+		urls = append(urls, sitemapURL{
+			Loc:     site.BaseURL + post.URL,
+			LastMod: sitemapDate(post.LastMod),
+			Images:  sitemapImages(site.BaseURL+post.URL, post.Image, string(post.Content)),
+		})
+	}
+
+	for _, page := range site.Pages {
+		urls = append(urls, sitemapURL{
+			Loc:     site.BaseURL + page.URL,
+			LastMod: sitemapDate(page.LastMod),
+			Images:  sitemapImages(site.BaseURL+page.URL, page.Image, string(page.Content)),
+		})
+	}
+
+	for _, tax := range site.Taxonomies {
+		urls = append(urls, sitemapURL{Loc: site.BaseURL + tax.URL(), LastMod: sitemapDate(site.LastMod())})
+		for _, term := range tax.Terms {
+			urls = append(urls, sitemapURL{Loc: site.BaseURL + term.URL, LastMod: sitemapDate(term.LastMod())})
+		}
+	}
+
+	return urls
+}
+
+func sitemapDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+var imgSrc = regexp.MustCompile(`<img\s[^>]*?\bsrc="([^"]+)"`)
+
+// sitemapImages returns the hero image followed by the images in content,
+// resolved against the page URL and without duplicates.
+func sitemapImages(pageURL, hero, content string) []sitemapImage {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	srcs := []string{hero}
+	for _, m := range imgSrc.FindAllStringSubmatch(content, -1) {
+		srcs = append(srcs, html.UnescapeString(m[1]))
+	}
+
+	var images []sitemapImage
+	seen := make(map[string]bool)
+	for _, src := range srcs {
+		if src == "" || strings.HasPrefix(src, "data:") {
+			continue
+		}
+		ref, err := url.Parse(src)
+		if err != nil {
+			continue
+		}
+		loc := base.ResolveReference(ref).String()
+		if !seen[loc] {
+			seen[loc] = true
+			images = append(images, sitemapImage{Loc: loc})
+		}
+	}
+	return images
+}
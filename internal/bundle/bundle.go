@@ -0,0 +1,198 @@
+// Package bundle manages the named script and style bundles that posts opt
+// in to with the js and css front matter keys.
+package bundle
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/integralist/integralist.co.uk/internal/asset"
+)
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Definition lists the files that make up a bundle. Entries are either
+// absolute URLs, used as-is, or paths relative to the assets directory.
+type Definition struct {
+	Scripts []string
+	Styles  []string
+}
+
+// Bundle is a named set of scripts and styles.
+type Bundle struct {
+	Name    string
+	Scripts []string
+	Styles  []string
+	// Inline is HTML inserted at the end of the body, from a
+	// templates/js/<name>.html file.
+	Inline template.HTML
+	// disabled bundles are still valid names but contribute no assets.
+	disabled bool
+}
+
+// Assets are the resolved bundle assets a single page needs.
+type Assets struct {
+	Scripts []string
+	Styles  []string
+	Inline  []template.HTML
+}
+
+// Registry holds every bundle known to the build.
+type Registry struct {
+	bundles map[string]*Bundle
+}
+
+// Load builds a Registry from defs and any *.html files in templateDir/js.
+// A name may only be defined once across both.
+func Load(templateDir string, defs map[string]Definition) (*Registry, error) {
+	r := &Registry{bundles: make(map[string]*Bundle)}
+
+	for name, def := range defs {
+		if !validName.MatchString(name) {
+			return nil, fmt.Errorf("bundle %q: name must be lowercase letters, digits and hyphens", name)
+		}
+		if len(def.Scripts) == 0 && len(def.Styles) == 0 {
+			return nil, fmt.Errorf("bundle %q: no scripts or styles", name)
+		}
+		r.bundles[name] = &Bundle{
+			Name:    name,
+			Scripts: slices.Clone(def.Scripts),
+			Styles:  slices.Clone(def.Styles),
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(templateDir, "js", "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".html")
+		if _, ok := r.bundles[name]; ok {
+			return nil, fmt.Errorf("bundle %q: defined in both config and %s", name, file)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		r.bundles[name] = &Bundle{Name: name, Inline: template.HTML(data)}
+	}
+	return r, nil
+}
+
+// Disable keeps name a valid bundle but drops its assets from every page,
+// for when the build already does the bundle's work. Unknown names are
+// ignored.
+func (r *Registry) Disable(name string) {
+	if b, ok := r.bundles[name]; ok {
+		b.disabled = true
+	}
+}
+
+// Check reports the first js or css name that does not refer to a bundle
+// providing scripts or styles respectively.
+func (r *Registry) Check(js, css []string) error {
+	for _, name := range js {
+		if b, ok := r.bundles[name]; !ok || (len(b.Scripts) == 0 && b.Inline == "") {
+			return fmt.Errorf("unknown js bundle %q (have %s)", name, r.names(func(b *Bundle) bool {
+				return len(b.Scripts) > 0 || b.Inline != ""
+			}))
+		}
+	}
+	for _, name := range css {
+		if b, ok := r.bundles[name]; !ok || len(b.Styles) == 0 {
+			return fmt.Errorf("unknown css bundle %q (have %s)", name, r.names(func(b *Bundle) bool {
+				return len(b.Styles) > 0
+			}))
+		}
+	}
+	return nil
+}
+
+func (r *Registry) names(keep func(*Bundle) bool) string {
+	var names []string
+	for name, b := range r.bundles {
+		if keep(b) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Assets resolves the scripts and styles for the named bundles, in order and
+// without duplicates. Unknown names are ignored; Check reports them at load
+// time. A nil Registry has no assets.
+func (r *Registry) Assets(js, css []string) Assets {
+	var a Assets
+	if r == nil {
+		return a
+	}
+	for _, name := range js {
+		b, ok := r.bundles[name]
+		if !ok || b.disabled {
+			continue
+		}
+		for _, s := range b.Scripts {
+			if !slices.Contains(a.Scripts, s) {
+				a.Scripts = append(a.Scripts, s)
+			}
+		}
+		if b.Inline != "" && !slices.Contains(a.Inline, b.Inline) {
+			a.Inline = append(a.Inline, b.Inline)
+		}
+	}
+	for _, name := range css {
+		b, ok := r.bundles[name]
+		if !ok || b.disabled {
+			continue
+		}
+		for _, s := range b.Styles {
+			if !slices.Contains(a.Styles, s) {
+				a.Styles = append(a.Styles, s)
+			}
+		}
+	}
+	return a
+}
+
+// Resolve points each local bundle file at its URL in m, e.g. js/prism.js
+// becomes /assets/js/prism.1a2b3c4d.js once fingerprinted. Bundles don't
+// copy or hash files themselves; asset.Copy has already done so.
+func (r *Registry) Resolve(m asset.Manifest) error {
+	resolve := func(b *Bundle, files []string) error {
+		for i, file := range files {
+			if isURL(file) {
+				continue
+			}
+			url, err := m.URL(file)
+			if err != nil {
+				return fmt.Errorf("bundle %q: %w", b.Name, err)
+			}
+			files[i] = url
+		}
+		return nil
+	}
+
+	for _, b := range r.bundles {
+		if err := resolve(b, b.Scripts); err != nil {
+			return err
+		}
+		if err := resolve(b, b.Styles); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "//")
+}
@@ -0,0 +1,96 @@
+package bundle_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/integralist/integralist.co.uk/internal/asset"
+	"github.com/integralist/integralist.co.uk/internal/bundle"
+)
+
+func setupTemplates(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "js"), 0o755)
+	os.WriteFile(filepath.Join(dir, "js", "mermaid.html"), []byte("<script>mermaid()</script>"), 0o644)
+	return dir
+}
+
+func TestCheck(t *testing.T) {
+	r, err := bundle.Load(setupTemplates(t), map[string]bundle.Definition{
+		"prism": {Styles: []string{"https://cdn.example.com/prism.css"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Check([]string{"mermaid"}, []string{"prism"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := r.Check([]string{"prism"}, nil); err == nil || !strings.Contains(err.Error(), `unknown js bundle "prism" (have mermaid)`) {
+		t.Errorf("error = %v, want style-only bundle rejected as js", err)
+	}
+	if err := r.Check(nil, []string{"nope"}); err == nil || !strings.Contains(err.Error(), `unknown css bundle "nope" (have prism)`) {
+		t.Errorf("error = %v, want unknown css bundle", err)
+	}
+}
+
+// Verifies that a disabled bundle is still accepted but adds nothing to a
+// page.
+func TestDisable(t *testing.T) {
+	r, err := bundle.Load(setupTemplates(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Disable("mermaid")
+
+	if err := r.Check([]string{"mermaid"}, nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if a := r.Assets([]string{"mermaid"}, nil); len(a.Scripts) != 0 || len(a.Inline) != 0 {
+		t.Errorf("assets = %+v, want none", a)
+	}
+}
+
+func TestLoad_DuplicateName(t *testing.T) {
+	_, err := bundle.Load(setupTemplates(t), map[string]bundle.Definition{
+		"mermaid": {Scripts: []string{"js/mermaid.js"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "defined in both") {
+		t.Errorf("error = %v, want duplicate definition error", err)
+	}
+}
+
+// Verifies that local files resolve to their manifest URLs, shared files
+// included, and that URLs are left alone.
+func TestResolve(t *testing.T) {
+	r, err := bundle.Load(t.TempDir(), map[string]bundle.Definition{
+		"a": {Scripts: []string{"js/shared.js", "https://cdn.example.com/a.js"}},
+		"b": {Scripts: []string{"js/shared.js"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Resolve(asset.Manifest{"js/shared.js": "/assets/js/shared.1a2b3c4d.js"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := r.Assets([]string{"a", "b"}, nil).Scripts
+	if len(got) != 2 || got[0] != "/assets/js/shared.1a2b3c4d.js" || got[1] != "https://cdn.example.com/a.js" {
+		t.Errorf("scripts = %v, want fingerprinted shared.js then the CDN URL", got)
+	}
+}
+
+func TestResolve_MissingFile(t *testing.T) {
+	r, err := bundle.Load(t.TempDir(), map[string]bundle.Definition{
+		"a": {Scripts: []string{"js/missing.js"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Resolve(asset.Manifest{}); err == nil || !strings.Contains(err.Error(), `bundle "a": unknown asset "js/missing.js"`) {
+		t.Errorf("error = %v, want missing file error naming the bundle", err)
+	}
+}
@@ -0,0 +1,292 @@
+// Package card draws the Open Graph images shown when a post is shared.
+package card
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Card dimensions, as recommended for og:image.
+const (
+	Width  = 1200
+	Height = 630
+)
+
+// version is part of each card's hash, so changing the layout below
+// invalidates cached cards.
+const version = 1
+
+// Card is what a post's card shows.
+type Card struct {
+	Title string
+	Date  time.Time
+	Tags  []Tag
+	// Site is the wordmark in the bottom right corner.
+	Site string
+	// Logo is an optional PNG drawn next to the wordmark.
+	Logo []byte
+}
+
+// Tag is a tag pill. Color is a #rrggbb hex color.
+type Tag struct {
+	Name  string
+	Color string
+}
+
+var (
+	background = hexColor("#1a2332")
+	text       = hexColor("#e4e4e7")
+	muted      = hexColor("#9ca3af")
+	accent     = hexColor("#E8887A")
+)
+
+const (
+	margin     = 64
+	pillH      = 48
+	pillPadX   = 20
+	pillGap    = 14
+	logoH      = 56
+	lineHeight = 1.2 // title line spacing, as a multiple of the font size
+)
+
+// titleSizes are tried largest first until the title fits.
+var titleSizes = []float64{72, 64, 56, 48}
+
+var fonts = sync.OnceValues(func() ([2]*opentype.Font, error) {
+	regular, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return [2]*opentype.Font{}, err
+	}
+	bold, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return [2]*opentype.Font{}, err
+	}
+	return [2]*opentype.Font{regular, bold}, nil
+})
+
+// Render draws c as a PNG.
+func Render(c Card) ([]byte, error) {
+	f, err := fonts()
+	if err != nil {
+		return nil, fmt.Errorf("loading fonts: %w", err)
+	}
+	regular, bold := f[0], f[1]
+
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	fill(img, img.Bounds(), background)
+
+	// A stripe down the left edge in the first tag's color.
+	stripe := accent
+	if len(c.Tags) > 0 {
+		stripe = hexColor(c.Tags[0].Color)
+	}
+	fill(img, image.Rect(0, 0, 16, Height), stripe)
+
+	// Tag pills along the top, as many as fit on one line.
+	pillFace, err := face(bold, 26)
+	if err != nil {
+		return nil, err
+	}
+	x := margin
+	for _, t := range c.Tags {
+		w := font.MeasureString(pillFace, t.Name).Ceil() + 2*pillPadX
+		if x+w > Width-margin {
+			break
+		}
+		r := image.Rect(x, margin, x+w, margin+pillH)
+		fillRounded(img, r, pillH/2, hexColor(t.Color))
+		drawText(img, pillFace, background, x+pillPadX, baseline(pillFace, r), t.Name)
+		x += w + pillGap
+	}
+
+	// The title, between the pills and the footer, shrunk until it fits and
+	// then cut short with an ellipsis.
+	titleTop := margin + pillH + 40
+	titleBottom := Height - margin - logoH - 32
+	var (
+		lines     []string
+		titleFace font.Face
+		lineH     int
+		fit       int
+	)
+	for _, size := range titleSizes {
+		if titleFace, err = face(bold, size); err != nil {
+			return nil, err
+		}
+		lineH = int(size * lineHeight)
+		fit = (titleBottom - titleTop) / lineH
+		if lines = wrap(titleFace, c.Title, Width-2*margin); len(lines) <= fit {
+			break
+		}
+	}
+	if len(lines) > fit {
+		lines = lines[:fit]
+		lines[fit-1] = ellipsis(titleFace, lines[fit-1], Width-2*margin)
+	}
+	for i, line := range lines {
+		drawText(img, titleFace, text, margin, titleTop+titleFace.Metrics().Ascent.Ceil()+i*lineH, line)
+	}
+
+	// The date bottom left, the logo and wordmark bottom right.
+	footFace, err := face(regular, 32)
+	if err != nil {
+		return nil, err
+	}
+	foot := image.Rect(margin, Height-margin-logoH, Width-margin, Height-margin)
+	if !c.Date.IsZero() {
+		drawText(img, footFace, muted, foot.Min.X, baseline(footFace, foot), c.Date.Format("January 2, 2006"))
+	}
+	siteFace, err := face(bold, 32)
+	if err != nil {
+		return nil, err
+	}
+	x = foot.Max.X - font.MeasureString(siteFace, c.Site).Ceil()
+	drawText(img, siteFace, text, x, baseline(siteFace, foot), c.Site)
+	if len(c.Logo) > 0 {
+		logo, err := png.Decode(bytes.NewReader(c.Logo))
+		if err != nil {
+			return nil, fmt.Errorf("decoding logo: %w", err)
+		}
+		b := logo.Bounds()
+		w := b.Dx() * logoH / max(b.Dy(), 1)
+		dst := image.Rect(x-16-w, foot.Min.Y, x-16, foot.Max.Y)
+		draw.CatmullRom.Scale(img, dst, logo, b, draw.Over, nil)
+	}
+
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := enc.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Cache renders cards through a directory of previous results, keyed by a
+// hash of the card, so unchanged posts aren't redrawn on every build.
+type Cache struct {
+	Dir string
+}
+
+// Render returns the cached PNG for c, drawing and caching it if needed.
+func (cc Cache) Render(c Card) ([]byte, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(append([]byte(strconv.Itoa(version)+"\x00"), data...))
+	file := filepath.Join(cc.Dir, hex.EncodeToString(sum[:])+".png")
+
+	if out, err := os.ReadFile(file); err == nil {
+		return out, nil
+	}
+
+	out, err := Render(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(cc.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating card cache: %w", err)
+	}
+	if err := os.WriteFile(file, out, 0o644); err != nil {
+		return nil, fmt.Errorf("writing card cache: %w", err)
+	}
+	return out, nil
+}
+
+func face(f *opentype.Font, size float64) (font.Face, error) {
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// wrap breaks s into lines no wider than width. A word wider than width is
+// left on a line of its own.
+func wrap(f font.Face, s string, width int) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(s) {
+		next := word
+		if line != "" {
+			next = line + " " + word
+		}
+		if line != "" && font.MeasureString(f, next).Ceil() > width {
+			lines = append(lines, line)
+			next = word
+		}
+		line = next
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// ellipsis shortens line, a word at a time, until it fits in width with an
+// ellipsis after it.
+func ellipsis(f font.Face, line string, width int) string {
+	for {
+		if font.MeasureString(f, line+"…").Ceil() <= width {
+			return line + "…"
+		}
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			return line + "…"
+		}
+		line = line[:i]
+	}
+}
+
+// baseline returns the y coordinate that vertically centres text in r.
+func baseline(f font.Face, r image.Rectangle) int {
+	m := f.Metrics()
+	return r.Min.Y + (r.Dy()+m.Ascent.Ceil()-m.Descent.Ceil())/2
+}
+
+func drawText(img draw.Image, f font.Face, c color.Color, x, y int, s string) {
+	d := font.Drawer{Dst: img, Src: image.NewUniform(c), Face: f, Dot: fixed.P(x, y)}
+	d.DrawString(s)
+}
+
+func fill(img draw.Image, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// fillRounded fills r with corners of the given radius.
+func fillRounded(img *image.RGBA, r image.Rectangle, radius int, c color.Color) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			// Distance past the nearest corner's centre, if in a corner.
+			dx := max(r.Min.X+radius-x-1, x-(r.Max.X-radius), 0)
+			dy := max(r.Min.Y+radius-y-1, y-(r.Max.Y-radius), 0)
+			if dx*dx+dy*dy <= radius*radius {
+				img.Set(x, y, c)
+			}
+		}
+	}
+}
+
+// hexColor parses a #rrggbb color, falling back to the accent color.
+func hexColor(s string) color.RGBA {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "#"), 16, 32)
+	if err != nil || len(s) != 7 {
+		return color.RGBA{0xE8, 0x88, 0x7A, 0xff}
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+}
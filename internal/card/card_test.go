@@ -0,0 +1,69 @@
+package card_test
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/integralist/integralist.co.uk/internal/card"
+)
+
+func testCard() card.Card {
+	return card.Card{
+		Title: "Understanding the bitwise operations in Go, with a title long enough to wrap over several lines of the card",
+		Date:  time.Date(2026, 4, 12, 0, 0, 0, 0, time.UTC),
+		Tags:  []card.Tag{{Name: "go", Color: "#D4796A"}, {Name: "performance", Color: "#6BA397"}},
+		Site:  "integralist.co.uk",
+	}
+}
+
+// Verifies that a card renders as a PNG of the Open Graph image size.
+func TestRender(t *testing.T) {
+	out, err := card.Render(testCard())
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("output is not a PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != card.Width || b.Dy() != card.Height {
+		t.Errorf("size = %dx%d, want %dx%d", b.Dx(), b.Dy(), card.Width, card.Height)
+	}
+}
+
+// Verifies that a cached card is reused and that changing the card draws a
+// new one.
+func TestCache(t *testing.T) {
+	cache := card.Cache{Dir: t.TempDir()}
+	c := testCard()
+	if _, err := cache.Render(c); err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(cache.Dir, "*.png"))
+	if len(files) != 1 {
+		t.Fatalf("got %d cached files, want 1", len(files))
+	}
+
+	if err := os.WriteFile(files[0], []byte("cached"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := cache.Render(c)
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	if string(out) != "cached" {
+		t.Error("cached card was redrawn")
+	}
+
+	c.Tags[0].Color = "#5B7FA5"
+	if out, _ := cache.Render(c); string(out) == "cached" {
+		t.Error("changed card was served from the cache")
+	}
+	if files, _ := filepath.Glob(filepath.Join(cache.Dir, "*.png")); len(files) != 2 {
+		t.Errorf("got %d cached files, want 2", len(files))
+	}
+}
@@ -0,0 +1,117 @@
+// Package compress writes precompressed .gz and .br siblings of the text
+// files in a built site, for hosts that can serve them directly.
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/andybalholm/brotli"
+)
+
+// extensions are the file types worth compressing.
+var extensions = map[string]bool{
+	".css":  true,
+	".html": true,
+	".json": true,
+	".txt":  true,
+	".xml":  true,
+}
+
+// Options configures Dir.
+type Options struct {
+	// MinSize skips files smaller than this many bytes, where compression
+	// saves too little to be worth a second request path.
+	MinSize int
+	// Brotli writes .br siblings as well as .gz.
+	Brotli bool
+}
+
+// Result counts the siblings Dir wrote.
+type Result struct {
+	Gzip   int
+	Brotli int
+}
+
+// Dir writes compressed siblings (index.html.gz, index.html.br) of each
+// eligible file under dir. A sibling that would not be smaller than its
+// source is skipped.
+func Dir(dir string, opts Options) (Result, error) {
+	var res Result
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !extensions[filepath.Ext(file)] {
+			return nil
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if len(data) < opts.MinSize {
+			return nil
+		}
+
+		gz, err := gzipBytes(data)
+		if err != nil {
+			return err
+		}
+		if len(gz) < len(data) {
+			if err := os.WriteFile(file+".gz", gz, 0o644); err != nil {
+				return err
+			}
+			res.Gzip++
+		}
+
+		if opts.Brotli {
+			br, err := brotliBytes(data)
+			if err != nil {
+				return err
+			}
+			if len(br) < len(data) {
+				if err := os.WriteFile(file+".br", br, 0o644); err != nil {
+					return err
+				}
+				res.Brotli++
+			}
+		}
+		return nil
+	})
+	return res, err
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// brotliLevel trades a little size for speed: the maximum level (11) is
+// several times slower and would dominate the build.
+const brotliLevel = 9
+
+func brotliBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := brotli.NewWriterLevel(&buf, brotliLevel)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,58 @@
+package compress_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+
+	"github.com/integralist/integralist.co.uk/internal/compress"
+)
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	page := strings.Repeat("<p>Hello, world.</p>\n", 200)
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte(page), 0o644)
+	os.WriteFile(filepath.Join(dir, "robots.txt"), []byte("User-agent: *\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "photo.png"), []byte(strings.Repeat("x", 4096)), 0o644)
+
+	res, err := compress.Dir(dir, compress.Options{MinSize: 1024, Brotli: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Gzip != 1 || res.Brotli != 1 {
+		t.Errorf("result = %+v, want one gzip and one brotli file", res)
+	}
+
+	gz, err := os.Open(filepath.Join(dir, "index.html.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gz.Close()
+	zr, err := gzip.NewReader(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(zr); string(got) != page {
+		t.Error("gzip sibling does not decompress to the original")
+	}
+
+	br, err := os.ReadFile(filepath.Join(dir, "index.html.br"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(brotli.NewReader(bytes.NewReader(br))); string(got) != page {
+		t.Error("brotli sibling does not decompress to the original")
+	}
+
+	for _, name := range []string{"robots.txt.gz", "photo.png.gz"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s written for a small or non-text file", name)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// Package config loads site-wide settings from a YAML file.
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds site-wide settings.
+type Config struct {
+	// Bundles are named sets of scripts and styles that posts opt in to
+	// with the js and css front matter keys.
+	Bundles    map[string]Bundle `yaml:"bundles"`
+	Cards      Cards             `yaml:"cards"`
+	Companions Companions        `yaml:"companions"`
+	Compress   Compress          `yaml:"compress"`
+	LLMs       LLMs              `yaml:"llms"`
+	Markdown   Markdown          `yaml:"markdown"`
+	// Minify strips insignificant whitespace and comments from the
+	// generated HTML and CSS.
+	Minify         bool           `yaml:"minify"`
+	Reading        Reading        `yaml:"reading"`
+	Sitemap        Sitemap        `yaml:"sitemap"`
+	StructuredData StructuredData `yaml:"structured_data"`
+	Summary        Summary        `yaml:"summary"`
+	// Taxonomies group posts by front matter keys other than tags.
+	Taxonomies []Taxonomy `yaml:"taxonomies"`
+	Updated    Updated    `yaml:"updated"`
+}
+
+// Bundle lists a bundle's files: absolute URLs, or paths relative to the
+// assets directory (e.g. js/prism.js), which are fingerprinted at build time.
+type Bundle struct {
+	Scripts []string `yaml:"scripts"`
+	Styles  []string `yaml:"styles"`
+}
+
+// Cards configures the Open Graph images drawn for posts without an image.
+type Cards struct {
+	Enabled  bool   `yaml:"enabled"`
+	CacheDir string `yaml:"cache_dir"`
+	// Logo is an optional PNG, relative to the assets directory, drawn next
+	// to the site name.
+	Logo string `yaml:"logo"`
+}
+
+// Companions configures the index.md Markdown file written next to each
+// post and page.
+type Companions struct {
+	// FrontMatter is "keep" (the source as written), "strip", or "header"
+	// (replaced with a title and metadata block in Markdown).
+	FrontMatter string `yaml:"front_matter"`
+}
+
+// Compress configures the precompressed .gz and .br siblings written next to
+// text outputs, for hosts (and the dev server) that serve them directly.
+type Compress struct {
+	Enabled bool `yaml:"enabled"`
+	MinSize int  `yaml:"min_size"` // bytes; smaller files are left alone
+	Brotli  bool `yaml:"brotli"`
+}
+
+// LLMs configures llms-full.txt and the per-tag tags/<slug>/llms.txt files.
+type LLMs struct {
+	// MaxBytes caps the size of each file. Entries that would go over it are
+	// left out, with a note. Zero means no limit.
+	MaxBytes int `yaml:"max_bytes"`
+}
+
+// Reading configures the reading time shown on posts: the words of prose
+// read per minute, plus a fixed time for each line of code and each image.
+type Reading struct {
+	WordsPerMinute     int `yaml:"words_per_minute"`
+	SecondsPerCodeLine int `yaml:"seconds_per_code_line"`
+	SecondsPerImage    int `yaml:"seconds_per_image"`
+}
+
+// Sitemap configures sitemap.xml.
+type Sitemap struct {
+	// MaxURLs is the most URLs written to one sitemap file. Above it,
+	// sitemap.xml becomes an index of sitemap-1.xml, sitemap-2.xml, and so on.
+	// The sitemap protocol allows at most 50,000.
+	MaxURLs int `yaml:"max_urls"`
+}
+
+// StructuredData configures the JSON-LD added to each page.
+type StructuredData struct {
+	// SearchURL is a URL template containing {search_term_string}. When set,
+	// the home page advertises it as the site's search.
+	SearchURL string `yaml:"search_url"`
+}
+
+// Summary configures the summaries of posts without a <!--more--> separator.
+type Summary struct {
+	Words int `yaml:"words"` // length of the summary
+}
+
+// Taxonomy declares a way of grouping posts besides tags, such as series.
+type Taxonomy struct {
+	// Key is the front matter key listing a post's terms, and the URL path
+	// of the taxonomy's pages: series gives /series/ and /series/<term>/.
+	Key string `yaml:"key"`
+	// Singular names one term in headings, e.g. "series" or "category".
+	Singular string `yaml:"singular"`
+	// Title heads the taxonomy's index page, e.g. "Series".
+	Title string `yaml:"title"`
+}
+
+// reservedTaxonomyKeys are URL paths the build already uses, and content
+// directories the loader already reads.
+var reservedTaxonomyKeys = map[string]bool{"assets": true, "pages": true, "posts": true, "tags": true}
+
+var taxonomyKey = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Updated configures how a post's last updated date is found when it has no
+// updated front matter.
+type Updated struct {
+	// Git uses the time of the last commit touching the post, when that is
+	// on a later day than its publish date.
+	Git bool `yaml:"git"`
+}
+
+// Markdown configures how Markdown is rendered to HTML.
+type Markdown struct {
+	// Alerts registers extra alert types (or overrides built-in icons),
+	// keyed by type name with the title icon as the value.
+	Alerts    map[string]string `yaml:"alerts"`
+	Anchors   Anchors           `yaml:"anchors"`
+	Diagrams  Diagrams          `yaml:"diagrams"`
+	Footnotes Footnotes         `yaml:"footnotes"`
+}
+
+// Anchors configures the permalink anchors added to headings.
+type Anchors struct {
+	Enabled  bool   `yaml:"enabled"`
+	MinLevel int    `yaml:"min_level"`
+	MaxLevel int    `yaml:"max_level"`
+	Class    string `yaml:"class"`
+	Symbol   string `yaml:"symbol"`
+	Position string `yaml:"position"` // "before" or "after" the heading text
+}
+
+// Diagrams configures build-time rendering of ```mermaid code blocks to
+// inline SVG. When disabled they are left for the client-side script.
+type Diagrams struct {
+	Enabled bool `yaml:"enabled"`
+	// Command reads diagram source on stdin and writes SVG to stdout.
+	Command  []string      `yaml:"command"`
+	CacheDir string        `yaml:"cache_dir"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// Footnotes configures footnote rendering.
+type Footnotes struct {
+	ReturnLink string `yaml:"return_link"` // HTML shown in the link back to the reference
+}
+
+// Default returns the settings used when no config file is present.
+func Default() Config {
+	return Config{
+		Cards: Cards{
+			CacheDir: ".cache/cards",
+		},
+		Companions: Companions{
+			FrontMatter: "keep",
+		},
+		Compress: Compress{
+			MinSize: 1024,
+			Brotli:  true,
+		},
+		Markdown: Markdown{
+			Anchors: Anchors{
+				Enabled:  true,
+				MinLevel: 2,
+				MaxLevel: 4,
+				Class:    "heading-anchor",
+				Symbol:   "#",
+				Position: "after",
+			},
+			Diagrams: Diagrams{
+				Command:  []string{"mmdc", "--input", "-", "--output", "-", "--outputFormat", "svg"},
+				CacheDir: ".cache/diagrams",
+				Timeout:  30 * time.Second,
+			},
+			Footnotes: Footnotes{
+				ReturnLink: "↩",
+			},
+		},
+		Reading: Reading{
+			WordsPerMinute:     200,
+			SecondsPerCodeLine: 2,
+			SecondsPerImage:    12,
+		},
+		Sitemap: Sitemap{
+			MaxURLs: 50000,
+		},
+		Summary: Summary{
+			Words: 50,
+		},
+	}
+}
+
+// Load reads the config file at path on top of Default. A missing file is not
+// an error.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (c Config) validate() error {
+	a := c.Markdown.Anchors
+	if a.Enabled {
+		if a.MinLevel < 1 || a.MaxLevel > 6 || a.MinLevel > a.MaxLevel {
+			return fmt.Errorf("markdown.anchors: invalid heading levels %d-%d", a.MinLevel, a.MaxLevel)
+		}
+		if a.Position != "before" && a.Position != "after" {
+			return fmt.Errorf("markdown.anchors.position: want \"before\" or \"after\", got %q", a.Position)
+		}
+	}
+	switch c.Companions.FrontMatter {
+	case "keep", "strip", "header":
+	default:
+		return fmt.Errorf("companions.front_matter: want \"keep\", \"strip\" or \"header\", got %q", c.Companions.FrontMatter)
+	}
+	if d := c.Markdown.Diagrams; d.Enabled && len(d.Command) == 0 {
+		return fmt.Errorf("markdown.diagrams.command: required when diagrams are enabled")
+	}
+	if u := c.StructuredData.SearchURL; u != "" && !strings.Contains(u, "{search_term_string}") {
+		return fmt.Errorf("structured_data.search_url: must contain {search_term_string}")
+	}
+	if c.Summary.Words < 1 {
+		return fmt.Errorf("summary.words: want at least 1, got %d", c.Summary.Words)
+	}
+	if r := c.Reading; r.WordsPerMinute < 1 || r.SecondsPerCodeLine < 0 || r.SecondsPerImage < 0 {
+		return fmt.Errorf("reading: want words_per_minute of at least 1 and no negative seconds")
+	}
+	seen := make(map[string]bool)
+	for i, t := range c.Taxonomies {
+		switch {
+		case !taxonomyKey.MatchString(t.Key):
+			return fmt.Errorf("taxonomies[%d].key: want lower-case letters, digits and dashes, got %q", i, t.Key)
+		case reservedTaxonomyKeys[t.Key]:
+			return fmt.Errorf("taxonomies[%d].key: %q is reserved", i, t.Key)
+		case seen[t.Key]:
+			return fmt.Errorf("taxonomies[%d].key: %q is declared twice", i, t.Key)
+		case t.Title == "" || t.Singular == "":
+			return fmt.Errorf("taxonomies[%d]: title and singular are required", i)
+		}
+		seen[t.Key] = true
+	}
+	if n := c.Sitemap.MaxURLs; n < 1 || n > 50000 {
+		return fmt.Errorf("sitemap.max_urls: want 1-50000, got %d", n)
+	}
+	return nil
+}
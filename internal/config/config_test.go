@@ -0,0 +1,97 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/integralist/integralist.co.uk/internal/config"
+)
+
+func TestLoad_MissingFileUsesDefaults(t *testing.T) {
+	cfg, err := config.Load(filepath.Join(t.TempDir(), "site.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Markdown.Anchors != config.Default().Markdown.Anchors {
+		t.Errorf("anchors = %+v, want defaults", cfg.Markdown.Anchors)
+	}
+}
+
+// Verifies that values in the file override defaults while unset keys keep them.
+func TestLoad_OverridesDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "site.yaml")
+	os.WriteFile(path, []byte("markdown:\n  anchors:\n    symbol: \"§\"\n    position: before\n"), 0o644)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a := cfg.Markdown.Anchors
+	if a.Symbol != "§" || a.Position != "before" {
+		t.Errorf("anchors = %+v, want overridden symbol and position", a)
+	}
+	if !a.Enabled || a.MinLevel != 2 || a.MaxLevel != 4 {
+		t.Errorf("anchors = %+v, want default levels kept", a)
+	}
+}
+
+func TestLoad_InvalidAnchorPosition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "site.yaml")
+	os.WriteFile(path, []byte("markdown:\n  anchors:\n    position: middle\n"), 0o644)
+
+	_, err := config.Load(path)
+	if err == nil || !strings.Contains(err.Error(), "position") {
+		t.Errorf("error = %v, want invalid position error", err)
+	}
+}
+
+func TestLoad_Diagrams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "site.yaml")
+	os.WriteFile(path, []byte("markdown:\n  diagrams:\n    enabled: true\n    timeout: 5s\n"), 0o644)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d := cfg.Markdown.Diagrams
+	if !d.Enabled || d.Timeout.Seconds() != 5 || len(d.Command) == 0 {
+		t.Errorf("diagrams = %+v, want enabled with default command and 5s timeout", d)
+	}
+
+	os.WriteFile(path, []byte("markdown:\n  diagrams:\n    enabled: true\n    command: []\n"), 0o644)
+	if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), "command") {
+		t.Errorf("error = %v, want missing command error", err)
+	}
+}
+
+func TestLoad_Taxonomies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "site.yaml")
+	os.WriteFile(path, []byte("taxonomies:\n  - key: series\n    title: Series\n    singular: series\n"), 0o644)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Taxonomies) != 1 || cfg.Taxonomies[0].Key != "series" {
+		t.Errorf("taxonomies = %+v, want series", cfg.Taxonomies)
+	}
+
+	invalid := []struct {
+		yaml string
+		want string
+	}{
+		{"taxonomies:\n  - key: tags\n    title: Tags\n    singular: tag\n", "reserved"},
+		{"taxonomies:\n  - key: pages\n    title: Pages\n    singular: page\n", "reserved"},
+		{"taxonomies:\n  - key: My Series\n    title: Series\n    singular: series\n", "lower-case"},
+		{"taxonomies:\n  - key: series\n", "required"},
+		{"taxonomies:\n  - {key: series, title: Series, singular: series}\n  - {key: series, title: Series, singular: series}\n", "twice"},
+	}
+	for _, tt := range invalid {
+		os.WriteFile(path, []byte(tt.yaml), 0o644)
+		if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("error = %v, want it to contain %q", err, tt.want)
+		}
+	}
+}
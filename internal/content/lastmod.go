@@ -0,0 +1,128 @@
+package content
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gitHistory is the commit times of the files under a directory, as of a
+// HEAD commit.
+type gitHistory struct {
+	head    string
+	shallow bool
+	times   map[string]time.Time
+}
+
+// histories caches gitHistory by directory, so a process that loads the
+// site again, to rebuild it, runs git log only when HEAD has moved.
+var histories = struct {
+	sync.Mutex
+	byDir map[string]gitHistory
+}{byDir: make(map[string]gitHistory)}
+
+// commitTimes returns the time of the last commit touching each file under
+// dir, keyed by path, and whether the repository is a shallow clone. The
+// times are empty when dir is not in a git work tree, git is not installed,
+// or the clone is shallow: there the oldest commit seems to add every file,
+// so each would get the same, wrong, time.
+func commitTimes(dir string) (map[string]time.Time, bool) {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--is-shallow-repository", "HEAD").Output()
+	if err != nil {
+		return map[string]time.Time{}, false
+	}
+	shallow, head, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	h := gitHistory{head: head, shallow: shallow == "true"}
+
+	histories.Lock()
+	defer histories.Unlock()
+	if cached, ok := histories.byDir[dir]; ok && cached.head == h.head {
+		return cached.times, cached.shallow
+	}
+	h.times = make(map[string]time.Time)
+	if !h.shallow {
+		h.times = gitLog(dir)
+	}
+	histories.byDir[dir] = h
+	return h.times, h.shallow
+}
+
+// gitLog returns the time of the last commit touching each file under dir,
+// keyed by path.
+func gitLog(dir string) map[string]time.Time {
+	times := make(map[string]time.Time)
+
+	// With --relative, names are relative to dir and only files under it
+	// are listed. Commits are newest first, so the first time seen for a
+	// file wins.
+	out, err := exec.Command("git", "-C", dir, "log", "--format=%x00%ct", "--name-only", "--relative").Output()
+	if err != nil {
+		return times
+	}
+
+	var current time.Time
+	for line := range strings.Lines(string(out)) {
+		line = strings.TrimSuffix(line, "\n")
+		if ts, ok := strings.CutPrefix(line, "\x00"); ok {
+			sec, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				return times
+			}
+			current = time.Unix(sec, 0).UTC()
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		file := filepath.Join(dir, filepath.FromSlash(line))
+		if _, ok := times[file]; !ok {
+			times[file] = current
+		}
+	}
+	return times
+}
+
+// lastMod returns when file last changed: updated if set, otherwise its last
+// commit time, otherwise its modification time. In a shallow clone every
+// file was just checked out, so its modification time is skipped too. The
+// result is never before published, so a post committed before its publish
+// date isn't reported as modified before it existed.
+func (l *loader) lastMod(file string, updated, published time.Time) time.Time {
+	t := updated
+	if t.IsZero() {
+		t = l.commitTimes[file]
+	}
+	if t.IsZero() && !l.shallow {
+		if info, err := os.Stat(file); err == nil {
+			t = info.ModTime().UTC()
+		}
+	}
+	if t.Before(published) {
+		return published
+	}
+	return t
+}
+
+// commitUpdate returns the last commit time of file if it falls on a later
+// day than published, and the zero time otherwise, so a post committed on
+// the day it was published doesn't show as updated.
+func (l *loader) commitUpdate(file string, published time.Time) time.Time {
+	t := l.commitTimes[file]
+	if t.Format(time.DateOnly) <= published.Format(time.DateOnly) {
+		return time.Time{}
+	}
+	return t
+}
+
+// updatedTime returns the updated front matter date, accepting lastmod (as used
+// by other generators) as an alias.
+func updatedTime(meta map[string]any) time.Time {
+	if t := getTime(meta, "updated"); !t.IsZero() {
+		return t
+	}
+	return getTime(meta, "lastmod")
+}
@@ -1,28 +1,109 @@
 package content
 
 import (
+	"bytes"
 	"fmt"
 	"html/template"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/integralist/integralist.co.uk/internal/bundle"
 	"github.com/integralist/integralist.co.uk/internal/model"
 	"github.com/integralist/integralist.co.uk/internal/parser"
 )
 
-var tagColors = []string{"#D4796A", "#D4A04A", "#6BA397", "#5B7FA5"}
+// sectionIndex is the file name (without extension) of a section's own page
+// within a nested pages directory.
+const sectionIndex = "_index"
+
+// Option configures LoadSite.
+type Option func(*loader)
+
+// WithConverter sets the Markdown converter used for posts and pages.
+func WithConverter(c *parser.Converter) Option {
+	return func(l *loader) {
+		l.converter = c
+	}
+}
+
+// WithBundles checks each post's js and css names against r, failing the
+// load on an unknown bundle.
+func WithBundles(r *bundle.Registry) Option {
+	return func(l *loader) {
+		l.bundles = r
+	}
+}
+
+// WithGitUpdated sets a post's Updated time from its last commit when it has
+// no updated front matter and was committed on a later day than its date.
+func WithGitUpdated() Option {
+	return func(l *loader) {
+		l.gitUpdated = true
+	}
+}
+
+// WithReadingSpeed sets how each post's reading time is estimated. The
+// default is model.DefaultReadingSpeed.
+func WithReadingSpeed(speed model.ReadingSpeed) Option {
+	return func(l *loader) {
+		l.readingSpeed = speed
+	}
+}
+
+// WithSummaryWords sets the length of a post's summary when it has no
+// <!--more--> separator. The default is 50 words.
+func WithSummaryWords(n int) Option {
+	return func(l *loader) {
+		l.summaryWords = n
+	}
+}
+
+// WithTaxonomies adds taxonomies besides tags. Only their Key, Singular and
+// Title are used; their terms are collected from the posts.
+func WithTaxonomies(taxonomies ...model.Taxonomy) Option {
+	return func(l *loader) {
+		l.taxonomies = taxonomies
+	}
+}
+
+type loader struct {
+	bundles      *bundle.Registry
+	commitTimes  map[string]time.Time
+	converter    *parser.Converter
+	gitUpdated   bool
+	readingSpeed model.ReadingSpeed
+	shallow      bool
+	summaryWords int
+	taxonomies   []model.Taxonomy
+}
 
 // LoadSite reads all content from contentDir and returns a populated Site.
-func LoadSite(contentDir string) (*model.Site, error) {
-	posts, err := loadPosts(filepath.Join(contentDir, "posts"))
+func LoadSite(contentDir string, opts ...Option) (*model.Site, error) {
+	l := &loader{readingSpeed: model.DefaultReadingSpeed, summaryWords: defaultSummaryWords}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.converter == nil {
+		c, err := parser.NewConverter(parser.Options{})
+		if err != nil {
+			return nil, err
+		}
+		l.converter = c
+	}
+	l.commitTimes, l.shallow = commitTimes(contentDir)
+
+	posts, err := l.loadPosts(filepath.Join(contentDir, "posts"))
 	if err != nil {
 		return nil, fmt.Errorf("loading posts: %w", err)
 	}
 
-	pages, err := loadPages(filepath.Join(contentDir, "pages"))
+	pages, err := l.loadPages(filepath.Join(contentDir, "pages"))
 	if err != nil {
 		return nil, fmt.Errorf("loading pages: %w", err)
 	}
@@ -30,19 +111,35 @@ func LoadSite(contentDir string) (*model.Site, error) {
 	sort.Slice(posts, func(i, j int) bool {
 		return posts[i].Date.After(posts[j].Date)
 	})
-	sort.Slice(pages, func(i, j int) bool {
-		if pages[i].NavOrder != pages[j].NavOrder {
-			return pages[i].NavOrder < pages[j].NavOrder
-		}
-		return pages[i].Title < pages[j].Title
-	})
+	sortPages(pages)
 
-	tags := collectTags(posts)
+	site := &model.Site{Posts: posts, Pages: pages}
+	taxonomies := append([]model.Taxonomy{{Key: model.TagsKey, Singular: "tag", Title: "Tags"}}, l.taxonomies...)
+	for _, t := range taxonomies {
+		tax := &model.Taxonomy{Key: t.Key, Singular: t.Singular, Title: t.Title}
+		files, err := l.loadTermFiles(tax, filepath.Join(contentDir, tax.Key))
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", tax.Key, err)
+		}
+		terms, warnings, err := collectTerms(tax, posts, files)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", tax.Key, err)
+		}
+		for _, page := range pages {
+			if strings.Split(page.Slug, "/")[0] == tax.Key {
+				return nil, fmt.Errorf("loading %s: page %s is at the taxonomy's URL", tax.Key, page.Slug)
+			}
+		}
+		tax.Terms = terms
+		site.Taxonomies = append(site.Taxonomies, tax)
+		site.Warnings = append(site.Warnings, warnings...)
+	}
+	site.Tags = site.Taxonomies[0].Terms
 
-	return &model.Site{Posts: posts, Pages: pages, Tags: tags}, nil
+	return site, nil
 }
 
-func loadPosts(dir string) ([]*model.Post, error) {
+func (l *loader) loadPosts(dir string) ([]*model.Post, error) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -57,7 +154,8 @@ func loadPosts(dir string) ([]*model.Post, error) {
 			continue
 		}
 
-		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		file := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(file)
 		if err != nil {
 			return nil, err
 		}
@@ -71,27 +169,76 @@ func loadPosts(dir string) ([]*model.Post, error) {
 			continue
 		}
 
+		js := getStringSlice(meta, "js")
+		css := getStringSlice(meta, "css")
+		if l.bundles != nil {
+			if err := l.bundles.Check(js, css); err != nil {
+				return nil, fmt.Errorf("%s: %w", e.Name(), err)
+			}
+		}
+
+		html, err := l.converter.Convert(parser.Document{
+			Name:      file,
+			FirstLine: bodyLine(data, body),
+			Body:      body,
+			Math:      slices.Contains(js, "math"),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		summary, content, excerpt := summarize(string(html), l.summaryWords)
+		words, codeLines, images := textStats(content)
+		description := getString(meta, "description")
+		if description == "" {
+			description = plainText(summary, descriptionLength)
+		}
+
 		slug := strings.TrimSuffix(e.Name(), ".md")
 		tags := getStringSlice(meta, "tags")
+		var terms map[string][]string
+		for _, t := range l.taxonomies {
+			if names := getTerms(meta, t.Key); len(names) > 0 {
+				if terms == nil {
+					terms = make(map[string][]string)
+				}
+				terms[t.Key] = names
+			}
+		}
 		keywords := getStringSlice(meta, "keywords")
 		if len(keywords) == 0 {
 			keywords = tags
 		}
+		date := getTime(meta, "date")
+		updated := updatedTime(meta)
+		if updated.IsZero() && l.gitUpdated {
+			updated = l.commitUpdate(file, date)
+		}
 		post := &model.Post{
 			Author:        getString(meta, "author"),
-			Content:       template.HTML(parser.MarkdownToHTML(body)),
-			Date:          getTime(meta, "date"),
-			Description:   getString(meta, "description"),
+			CodeLines:     codeLines,
+			Content:       template.HTML(content),
+			CSS:           css,
+			Date:          date,
+			Description:   description,
+			Excerpt:       excerpt,
 			Image:         getString(meta, "image"),
 			ImagePosition: getString(meta, "image_position"),
-			JS:            getStringSlice(meta, "js"),
+			Images:        images,
+			JS:            js,
 			Keywords:      keywords,
+			LastMod:       l.lastMod(file, updated, date),
 			MarkdownURL:   "/posts/" + slug + "/index.md",
+			ReadingTime:   l.readingSpeed.Minutes(words, codeLines, images),
 			Slug:          slug,
 			SourceMD:      data,
+			Summary:       template.HTML(summary),
 			Tags:          tags,
+			Terms:         terms,
 			Title:         getString(meta, "title"),
+			Updated:       updated,
 			URL:           "/posts/" + slug + "/",
+			WordCount:     words,
 		}
 		posts = append(posts, post)
 	}
@@ -99,9 +246,8 @@ func loadPosts(dir string) ([]*model.Post, error) {
 	return posts, nil
 }
 
-func loadPages(dir string) ([]*model.Page, error) {
-	entries, err := os.ReadDir(dir)
-	if err != nil {
+func (l *loader) loadPages(dir string) ([]*model.Page, error) {
+	if _, err := os.Stat(dir); err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
 		}
@@ -109,77 +255,139 @@ func loadPages(dir string) ([]*model.Page, error) {
 	}
 
 	var pages []*model.Page
-	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
-			continue
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".md") {
+			return nil
 		}
 
-		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		rel, err := filepath.Rel(dir, file)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
 		}
 
 		meta, body, err := parser.ParseFrontMatter(data)
 		if err != nil {
-			return nil, fmt.Errorf("parsing %s: %w", e.Name(), err)
+			return fmt.Errorf("parsing %s: %w", rel, err)
 		}
 
 		if getBool(meta, "draft") {
-			continue
+			return nil
 		}
 
-		slug := strings.TrimSuffix(e.Name(), ".md")
+		html, err := l.converter.Convert(parser.Document{
+			Name:      file,
+			FirstLine: bodyLine(data, body),
+			Body:      body,
+		})
+		if err != nil {
+			return err
+		}
+
+		// A section's _index.md renders at the section's own URL, so
+		// pages/projects/_index.md becomes /projects/.
+		slug := strings.TrimSuffix(rel, ".md")
+		section := false
+		if path.Base(slug) == sectionIndex {
+			slug = path.Dir(slug)
+			section = true
+			if slug == "." {
+				return fmt.Errorf("parsing %s: section index not allowed at the pages root", rel)
+			}
+		}
+
+		schema := getString(meta, "schema")
+		person := getPerson(meta, "person")
+		switch schema {
+		case "":
+		case "ProfilePage":
+			if person == nil || person.Name == "" {
+				return fmt.Errorf("parsing %s: schema ProfilePage needs a person with a name", rel)
+			}
+		default:
+			return fmt.Errorf("parsing %s: unknown schema %q (want ProfilePage)", rel, schema)
+		}
+
+		updated := updatedTime(meta)
 		page := &model.Page{
-			Content:       template.HTML(parser.MarkdownToHTML(body)),
+			Content:       template.HTML(html),
 			Description:   getString(meta, "description"),
 			Image:         getString(meta, "image"),
 			ImagePosition: getString(meta, "image_position"),
 			Keywords:      getStringSlice(meta, "keywords"),
+			LastMod:       l.lastMod(file, updated, time.Time{}),
 			MarkdownURL:   "/" + slug + "/index.md",
+			NavExclude:    !getBoolDefault(meta, "nav", true),
 			NavOrder:      getInt(meta, "nav_order"),
+			Person:        person,
+			Schema:        schema,
+			Section:       section,
 			Slug:          slug,
 			SourceMD:      data,
 			Title:         getString(meta, "title"),
+			Updated:       updated,
 			URL:           "/" + slug + "/",
 		}
 		pages = append(pages, page)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	linkPages(pages)
 	return pages, nil
 }
 
-func collectTags(posts []*model.Post) []*model.Tag {
-	tagMap := make(map[string]*model.Tag)
-
-	for _, p := range posts {
-		for _, name := range p.Tags {
-			slug := model.Slugify(name)
-			tag, ok := tagMap[slug]
-			if !ok {
-				tag = &model.Tag{
-					Name: name,
-					Slug: slug,
-					URL:  "/tags/" + slug + "/",
-				}
-				tagMap[slug] = tag
+// linkPages connects each page to its nearest ancestor page, so that
+// pages/projects/foo.md becomes a child of pages/projects/_index.md.
+func linkPages(pages []*model.Page) {
+	bySlug := make(map[string]*model.Page, len(pages))
+	for _, p := range pages {
+		bySlug[p.Slug] = p
+	}
+
+	for _, p := range pages {
+		for dir := path.Dir(p.Slug); dir != "."; dir = path.Dir(dir) {
+			if parent, ok := bySlug[dir]; ok {
+				p.Parent = parent
+				parent.Children = append(parent.Children, p)
+				break
 			}
-			tag.Posts = append(tag.Posts, p)
 		}
 	}
 
-	tags := make([]*model.Tag, 0, len(tagMap))
-	for _, t := range tagMap {
-		tags = append(tags, t)
+	for _, p := range pages {
+		sortPages(p.Children)
 	}
-	sort.Slice(tags, func(i, j int) bool {
-		return tags[i].Name < tags[j].Name
+}
+
+func sortPages(pages []*model.Page) {
+	sort.Slice(pages, func(i, j int) bool {
+		if pages[i].NavOrder != pages[j].NavOrder {
+			return pages[i].NavOrder < pages[j].NavOrder
+		}
+		return pages[i].Title < pages[j].Title
 	})
+}
 
-	for i, t := range tags {
-		t.Color = tagColors[i%len(tagColors)]
+// bodyLine returns the 1-based line of data on which body starts. body is
+// always a suffix of data (less trailing whitespace), so the last match is
+// the right one even if the same text also appears in the front matter.
+func bodyLine(data, body []byte) int {
+	i := bytes.LastIndex(data, body)
+	if i < 0 {
+		return 1
 	}
-
-	return tags
+	return bytes.Count(data[:i], []byte("\n")) + 1
 }
 
 func getString(m map[string]any, key string) string {
@@ -237,6 +445,18 @@ func getBool(m map[string]any, key string) bool {
 	return ok && b
 }
 
+func getBoolDefault(m map[string]any, key string, def bool) bool {
+	v, ok := m[key]
+	if !ok {
+		return def
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return def
+	}
+	return b
+}
+
 func getStringSlice(m map[string]any, key string) []string {
 	v, ok := m[key]
 	if !ok {
@@ -254,3 +474,16 @@ func getStringSlice(m map[string]any, key string) []string {
 	}
 	return result
 }
+
+// getPerson reads a person map (name, job_title and same_as) from m.
+func getPerson(m map[string]any, key string) *model.Person {
+	v, ok := m[key].(map[string]any)
+	if !ok {
+		return nil
+	}
+	return &model.Person{
+		Name:     getString(v, "name"),
+		JobTitle: getString(v, "job_title"),
+		SameAs:   getStringSlice(v, "same_as"),
+	}
+}
@@ -2,10 +2,15 @@ package content_test
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/integralist/integralist.co.uk/internal/content"
+	"github.com/integralist/integralist.co.uk/internal/model"
 )
 
 func writeFile(t *testing.T, dir, name, data string) {
@@ -214,6 +219,169 @@ B.`)
 	}
 }
 
+func TestLoadSite_TagFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "posts/a.md", "---\ntitle: A\ndate: 2026-01-01\ntags: [golang, go]\n---\nA.")
+	writeFile(t, dir, "posts/b.md", "---\ntitle: B\ndate: 2026-02-01\ntags: [rust]\n---\nB.")
+	writeFile(t, dir, "tags/go.md", "---\nname: Go\ncolor: \"#00ADD8\"\naliases: [golang]\nparents: [programming]\n---\nPosts about *Go*.")
+	writeFile(t, dir, "tags/rust.md", "---\nparents: [Programming]\ndescription: Rust posts.\n---\n")
+	writeFile(t, dir, "tags/programming.md", "---\nname: Programming\n---\n")
+
+	site, err := content.LoadSite(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(site.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", site.Warnings)
+	}
+	if len(site.Tags) != 3 {
+		t.Fatalf("got %d tags, want 3 (Go, Programming, rust)", len(site.Tags))
+	}
+	goTag, programming, rust := site.Tags[0], site.Tags[1], site.Tags[2]
+
+	if goTag.Name != "Go" || goTag.Slug != "go" || goTag.Color != "#00ADD8" {
+		t.Errorf("go tag = %q %q %q, want name, slug and color from its file", goTag.Name, goTag.Slug, goTag.Color)
+	}
+	if want := "<p>Posts about <em>Go</em>.</p>"; string(goTag.Content) != want || goTag.Description != "Posts about Go." {
+		t.Errorf("go Content, Description = %q, %q, want %q and its text", goTag.Content, goTag.Description, want)
+	}
+	if rust.Description != "Rust posts." {
+		t.Errorf("rust Description = %q, want front matter", rust.Description)
+	}
+	// golang is merged into go, and the post's tags name the tag.
+	if len(goTag.Posts) != 1 || !slices.Equal(site.Posts[1].Tags, []string{"Go"}) {
+		t.Errorf("go has %d posts and post A tags %v, want 1 and [Go]", len(goTag.Posts), site.Posts[1].Tags)
+	}
+	// A parent lists the posts of its children, newest first.
+	if len(programming.Posts) != 2 || programming.Posts[0].Title != "B" {
+		t.Errorf("programming has %d posts, want B then A", len(programming.Posts))
+	}
+	if len(programming.Children) != 2 || programming.Children[0] != goTag || rust.Parents[0] != programming {
+		t.Errorf("programming children = %v, want Go and rust", programming.Children)
+	}
+}
+
+func TestLoadSite_TagCollisionWarning(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "posts/a.md", "---\ntitle: A\ndate: 2026-01-01\ntags: [Go]\n---\nA.")
+	writeFile(t, dir, "posts/b.md", "---\ntitle: B\ndate: 2026-02-01\ntags: [go, unused-elsewhere]\n---\nB.")
+	writeFile(t, dir, "tags/empty.md", "---\nname: Empty\n---\n")
+
+	site, err := content.LoadSite(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		`tags "go", "Go" share the URL /tags/go/; using "go" (set name in content/tags/go.md to choose)`,
+		"tags/empty.md: no posts have this tag",
+	}
+	if !slices.Equal(site.Warnings, want) {
+		t.Errorf("Warnings = %q, want %q", site.Warnings, want)
+	}
+	if len(site.Tags) != 2 {
+		t.Errorf("got %d tags, want 2 (go, unused-elsewhere)", len(site.Tags))
+	}
+}
+
+func TestLoadSite_TagFileErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "unknown parent",
+			files: map[string]string{"tags/go.md": "---\nparents: [nope]\n---\n"},
+			want:  `tags/go.md: unknown parent tag "nope"`,
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"tags/go.md":   "---\nparents: [rust]\n---\n",
+				"tags/rust.md": "---\nparents: [go]\n---\n",
+			},
+			want: "is its own ancestor",
+		},
+		{
+			name: "alias of another tag",
+			files: map[string]string{
+				"tags/go.md":   "---\naliases: [rust]\n---\n",
+				"tags/rust.md": "---\n---\n",
+			},
+			want: `tags/go.md: alias "rust" is a tag of its own in tags/rust.md`,
+		},
+		{
+			name:  "bad color",
+			files: map[string]string{"tags/go.md": "---\ncolor: blue\n---\n"},
+			want:  `tags/go.md: color must be #rrggbb, got "blue"`,
+		},
+		{
+			name:  "file name not a slug",
+			files: map[string]string{"tags/Go.md": "---\n---\n"},
+			want:  "tags/Go.md: file name must be a tag slug, e.g. go.md",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, "posts/a.md", "---\ntitle: A\ndate: 2026-01-01\ntags: [go, rust]\n---\nA.")
+			for name, data := range tt.files {
+				writeFile(t, dir, name, data)
+			}
+			_, err := content.LoadSite(dir)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSite_Taxonomies(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "posts/a.md", "---\ntitle: A\ndate: 2026-01-01\nseries: Go Concurrency\ncategories: [Tutorials, Notes]\n---\nA.")
+	writeFile(t, dir, "posts/b.md", "---\ntitle: B\ndate: 2026-02-01\nseries: go-concurrency\ntags: [go]\n---\nB.")
+	writeFile(t, dir, "series/go-concurrency.md", "---\nname: Go Concurrency\naliases: [goroutines]\n---\nA series on goroutines.")
+
+	series := model.Taxonomy{Key: "series", Singular: "series", Title: "Series"}
+	categories := model.Taxonomy{Key: "categories", Singular: "category", Title: "Categories"}
+	site, err := content.LoadSite(dir, content.WithTaxonomies(series, categories))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(site.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", site.Warnings)
+	}
+
+	if len(site.Taxonomies) != 3 || site.Taxonomies[0].Key != model.TagsKey || len(site.Tags) != 1 {
+		t.Fatalf("got %d taxonomies and %d tags, want tags, series and categories, and 1 tag", len(site.Taxonomies), len(site.Tags))
+	}
+	s, c := site.Taxonomies[1], site.Taxonomies[2]
+	if len(s.Terms) != 1 || s.Terms[0].URL != "/series/go-concurrency/" || len(s.Terms[0].Posts) != 2 {
+		t.Fatalf("series terms = %v, want go-concurrency with both posts", s.Terms)
+	}
+	if s.Terms[0].Description != "A series on goroutines." {
+		t.Errorf("series Description = %q, want it from series/go-concurrency.md", s.Terms[0].Description)
+	}
+	// A single name works as well as a list, and names are made canonical.
+	if got := site.Posts[0].Terms["series"]; !slices.Equal(got, []string{"Go Concurrency"}) {
+		t.Errorf("post B series = %v, want [Go Concurrency]", got)
+	}
+	if len(c.Terms) != 2 || c.Terms[0].Name != "Notes" || c.Terms[1].URL != "/categories/tutorials/" {
+		t.Errorf("categories terms = %v, want Notes and Tutorials", c.Terms)
+	}
+}
+
+func TestLoadSite_TaxonomyPageCollision(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "pages/series.md", "---\ntitle: Series\n---\nMy series.")
+
+	_, err := content.LoadSite(dir, content.WithTaxonomies(model.Taxonomy{Key: "series", Singular: "series", Title: "Series"}))
+	if err == nil || !strings.Contains(err.Error(), "page series is at the taxonomy's URL") {
+		t.Errorf("error = %v, want a collision with the series page", err)
+	}
+}
+
 func TestLoadSite_Pages(t *testing.T) {
 	dir := t.TempDir()
 	os.MkdirAll(filepath.Join(dir, "posts"), 0o755)
@@ -273,6 +441,245 @@ Content.`)
 	}
 }
 
+// Verifies that LastMod comes from updated (or lastmod) front matter, falls
+// back to the file's modification time outside a git repository, and is
+// never before the publish date.
+func TestLoadSite_LastMod(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "posts/updated.md", "---\ntitle: Updated\ndate: 2026-04-12\nupdated: 2026-05-01\n---\nContent.")
+	writeFile(t, dir, "posts/lastmod.md", "---\ntitle: Lastmod\ndate: 2026-04-11\nlastmod: 2026-05-02\n---\nContent.")
+	writeFile(t, dir, "posts/mtime.md", "---\ntitle: Mtime\ndate: 2026-04-10\n---\nContent.")
+	writeFile(t, dir, "posts/future.md", "---\ntitle: Future\ndate: 2030-01-01\n---\nContent.")
+	writeFile(t, dir, "pages/about.md", "---\ntitle: About\n---\nContent.")
+	mtime := time.Date(2026, 6, 3, 12, 0, 0, 0, time.UTC)
+	for _, name := range []string{"posts/mtime.md", "posts/future.md", "pages/about.md"} {
+		os.Chtimes(filepath.Join(dir, name), mtime, mtime)
+	}
+
+	site, err := content.LoadSite(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"updated": "2026-05-01",
+		"lastmod": "2026-05-02",
+		"mtime":   "2026-06-03",
+		"future":  "2030-01-01",
+	}
+	for _, p := range site.Posts {
+		if got := p.LastMod.Format("2006-01-02"); got != want[p.Slug] {
+			t.Errorf("%s: LastMod = %s, want %s", p.Slug, got, want[p.Slug])
+		}
+	}
+	if u := site.Posts[1].Updated; u.Format("2006-01-02") != "2026-05-01" {
+		t.Errorf("Updated = %v, want 2026-05-01", u)
+	}
+	if got := site.Pages[0].LastMod; !got.Equal(mtime) {
+		t.Errorf("page LastMod = %v, want %v", got, mtime)
+	}
+	if got := site.LastMod().Format("2006-01-02"); got != "2030-01-01" {
+		t.Errorf("site LastMod = %s, want newest post", got)
+	}
+}
+
+// Verifies that WithGitUpdated takes Updated from the last commit, but only
+// when it is on a later day than the publish date.
+func TestLoadSite_GitUpdated(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	writeFile(t, dir, "posts/edited.md", "---\ntitle: Edited\ndate: 2026-04-10\n---\nContent.")
+	writeFile(t, dir, "posts/same-day.md", "---\ntitle: Same Day\ndate: 2026-06-01\n---\nContent.")
+	writeFile(t, dir, "posts/front-matter.md", "---\ntitle: Front Matter\ndate: 2026-04-10\nupdated: 2026-05-05\n---\nContent.")
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+			"GIT_AUTHOR_DATE=2026-06-01T12:00:00Z", "GIT_COMMITTER_DATE=2026-06-01T12:00:00Z",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "posts")
+
+	site, err := content.LoadSite(dir, content.WithGitUpdated())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"edited":       "2026-06-01T12:00:00Z",
+		"same-day":     "0001-01-01T00:00:00Z",
+		"front-matter": "2026-05-05T00:00:00Z",
+	}
+	for _, p := range site.Posts {
+		if got := p.Updated.Format(time.RFC3339); got != want[p.Slug] {
+			t.Errorf("%s: Updated = %s, want %s", p.Slug, got, want[p.Slug])
+		}
+	}
+
+	site, err = content.LoadSite(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, p := range site.Posts {
+		if p.Slug == "edited" && !p.Updated.IsZero() {
+			t.Errorf("Updated = %v without WithGitUpdated, want zero", p.Updated)
+		}
+		if p.Slug == "edited" && p.LastMod.Format(time.RFC3339) != "2026-06-01T12:00:00Z" {
+			t.Errorf("LastMod = %v, want last commit time", p.LastMod)
+		}
+	}
+}
+
+// Verifies that LastMod follows new commits when the site is loaded again,
+// and that a shallow clone falls back to the front matter dates.
+func TestLoadSite_GitHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(dir, date string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+			"GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date,
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	lastMod := func(dir string) string {
+		t.Helper()
+		site, err := content.LoadSite(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return site.Posts[0].LastMod.Format(time.RFC3339)
+	}
+
+	writeFile(t, dir, "posts/post.md", "---\ntitle: Post\ndate: 2026-04-10\n---\nContent.")
+	git(dir, "2026-06-01T12:00:00Z", "init", "-q")
+	git(dir, "2026-06-01T12:00:00Z", "add", ".")
+	git(dir, "2026-06-01T12:00:00Z", "commit", "-q", "-m", "post")
+	if got := lastMod(dir); got != "2026-06-01T12:00:00Z" {
+		t.Errorf("LastMod = %s, want first commit time", got)
+	}
+
+	writeFile(t, dir, "posts/post.md", "---\ntitle: Post\ndate: 2026-04-10\n---\nEdited.")
+	git(dir, "2026-07-01T12:00:00Z", "commit", "-q", "-am", "edit")
+	if got := lastMod(dir); got != "2026-07-01T12:00:00Z" {
+		t.Errorf("LastMod = %s after a new commit, want its time", got)
+	}
+
+	clone := filepath.Join(t.TempDir(), "clone")
+	git(dir, "2026-07-01T12:00:00Z", "clone", "-q", "--depth", "1", "file://"+dir, clone)
+	if got := lastMod(clone); got != "2026-04-10T00:00:00Z" {
+		t.Errorf("LastMod = %s in a shallow clone, want the publish date", got)
+	}
+}
+
+func TestLoadSite_PageSchema(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "pages/resume.md", "---\ntitle: Resume\nschema: ProfilePage\nperson:\n  name: Mark\n  job_title: Engineer\n  same_as: [https://github.com/integralist]\n---\nContent.")
+
+	site, err := content.LoadSite(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p := site.Pages[0]
+	if p.Schema != "ProfilePage" || p.Person == nil || p.Person.Name != "Mark" || p.Person.JobTitle != "Engineer" || len(p.Person.SameAs) != 1 {
+		t.Errorf("page = %+v, person = %+v, want ProfilePage for Mark", p, p.Person)
+	}
+
+	for src, want := range map[string]string{
+		"---\ntitle: Resume\nschema: ProfilePage\n---\n": "needs a person",
+		"---\ntitle: Resume\nschema: AboutPage\n---\n":   `unknown schema "AboutPage"`,
+	} {
+		writeFile(t, dir, "pages/resume.md", src)
+		if _, err := content.LoadSite(dir); err == nil || !strings.Contains(err.Error(), "resume.md") || !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want %q for resume.md", err, want)
+		}
+	}
+}
+
+// Verifies that a <!--more--> separator marks the summary and is removed
+// from the content.
+func TestLoadSite_SummaryExcerpt(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "posts/more.md", "---\ntitle: More\ndate: 2026-04-12\n---\nThe *intro*.\n\nSecond paragraph.\n\n<!--more-->\n\nThe rest.\n")
+
+	site, err := content.LoadSite(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p := site.Posts[0]
+	if want := "<p>The <em>intro</em>.</p>\n\n<p>Second paragraph.</p>"; string(p.Summary) != want || !p.Excerpt {
+		t.Errorf("Summary = %q (excerpt %v), want %q", p.Summary, p.Excerpt, want)
+	}
+	if strings.Contains(string(p.Content), "more") || !strings.Contains(string(p.Content), "The rest.") {
+		t.Errorf("Content = %q, want separator removed", p.Content)
+	}
+	if p.Description != "The intro. Second paragraph." {
+		t.Errorf("Description = %q, want plain-text summary", p.Description)
+	}
+}
+
+// Verifies that an automatic summary is cut after its first words without
+// breaking the markup, and stops at the first heading or code block.
+func TestLoadSite_SummaryTruncated(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "posts/long.md", "---\ntitle: Long\ndate: 2026-04-12\ndescription: Written by hand.\n---\n![hero](/a.png)\n\nOne two [three four five](/x/) six.\n")
+	writeFile(t, dir, "posts/short.md", "---\ntitle: Short\ndate: 2026-04-11\n---\nJust the intro.\n\n## Heading\n\nMore words.\n\n```\ncode\n```\n")
+
+	site, err := content.LoadSite(dir, content.WithSummaryWords(4))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	long, short := site.Posts[0], site.Posts[1]
+	if want := `<p>One two <a href="/x/">three four…</a></p>`; string(long.Summary) != want || long.Excerpt {
+		t.Errorf("Summary = %q (excerpt %v), want %q", long.Summary, long.Excerpt, want)
+	}
+	if long.Description != "Written by hand." {
+		t.Errorf("Description = %q, want front matter kept", long.Description)
+	}
+	if want := "<p>Just the intro.</p>"; string(short.Summary) != want {
+		t.Errorf("Summary = %q, want %q", short.Summary, want)
+	}
+}
+
+func TestLoadSite_ReadingStats(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "posts/stats.md", "---\ntitle: Stats\ndate: 2026-04-12\n---\nOne two — <em>three</em> four.\n\n![diagram](/a.png)\n\n```go\nfunc main() {\n\n\tprintln(\"not counted as words\")\n}\n```\n")
+
+	speed := model.ReadingSpeed{WordsPerMinute: 2, SecondsPerCodeLine: 10, SecondsPerImage: 30}
+	site, err := content.LoadSite(dir, content.WithReadingSpeed(speed))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	post := site.Posts[0]
+	if post.WordCount != 4 || post.CodeLines != 3 || post.Images != 1 {
+		t.Errorf("WordCount, CodeLines, Images = %d, %d, %d, want 4, 3, 1", post.WordCount, post.CodeLines, post.Images)
+	}
+	// 4 words at 2 a minute, plus 30s of code and 30s for the image.
+	if post.ReadingTime != 3 {
+		t.Errorf("ReadingTime = %d, want 3", post.ReadingTime)
+	}
+}
+
 func TestLoadSite_IgnoresNonMarkdown(t *testing.T) {
 	dir := t.TempDir()
 	os.MkdirAll(filepath.Join(dir, "posts"), 0o755)
@@ -289,3 +696,142 @@ func TestLoadSite_IgnoresNonMarkdown(t *testing.T) {
 		t.Errorf("got %d posts, want 0 (non-markdown ignored)", len(site.Posts))
 	}
 }
+
+// Verifies that nested page directories build a parent/child hierarchy.
+func TestLoadSite_NestedPages(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "posts"), 0o755)
+
+	writeFile(t, dir, "pages/about.md", `---
+title: "About"
+nav_order: 1
+---
+About me.`)
+
+	writeFile(t, dir, "pages/projects/_index.md", `---
+title: "Projects"
+nav_order: 2
+---
+Things I've built.`)
+
+	writeFile(t, dir, "pages/projects/foo.md", `---
+title: "Foo"
+---
+Foo project.`)
+
+	writeFile(t, dir, "pages/colophon.md", `---
+title: "Colophon"
+nav: false
+---
+How this site is built.`)
+
+	site, err := content.LoadSite(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(site.Pages) != 4 {
+		t.Fatalf("got %d pages, want 4", len(site.Pages))
+	}
+
+	bySlug := make(map[string]*model.Page)
+	for _, p := range site.Pages {
+		bySlug[p.Slug] = p
+	}
+
+	projects, ok := bySlug["projects"]
+	if !ok {
+		t.Fatal("section index page missing")
+	}
+	if projects.URL != "/projects/" {
+		t.Errorf("section url = %q, want %q", projects.URL, "/projects/")
+	}
+	if !projects.Section {
+		t.Error("section index page not marked as a section")
+	}
+
+	foo, ok := bySlug["projects/foo"]
+	if !ok {
+		t.Fatal("nested page missing")
+	}
+	if foo.URL != "/projects/foo/" {
+		t.Errorf("nested url = %q, want %q", foo.URL, "/projects/foo/")
+	}
+	if foo.MarkdownURL != "/projects/foo/index.md" {
+		t.Errorf("nested markdown url = %q, want %q", foo.MarkdownURL, "/projects/foo/index.md")
+	}
+	if foo.Parent != projects {
+		t.Errorf("nested page parent = %v, want projects section", foo.Parent)
+	}
+	if len(projects.Children) != 1 || projects.Children[0] != foo {
+		t.Errorf("section children = %v, want [foo]", projects.Children)
+	}
+
+	crumbs := foo.Breadcrumbs()
+	if len(crumbs) != 2 || crumbs[0].URL != "/projects/" || crumbs[1].URL != "/projects/foo/" {
+		t.Errorf("breadcrumbs = %v, want projects then foo", crumbs)
+	}
+
+	var nav []string
+	for _, p := range site.NavPages() {
+		nav = append(nav, p.Slug)
+	}
+	if strings.Join(nav, ",") != "about,projects" {
+		t.Errorf("nav pages = %v, want [about projects]", nav)
+	}
+}
+
+// Verifies that a nested page without a section index has no parent and is
+// kept out of the top-level navigation.
+func TestLoadSite_NestedPageWithoutSectionIndex(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, dir, "pages/about.md", `---
+title: "About"
+---
+About me.`)
+
+	writeFile(t, dir, "pages/notes/bar.md", `---
+title: "Bar"
+---
+A note.`)
+
+	site, err := content.LoadSite(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, p := range site.Pages {
+		if p.Slug == "notes/bar" && p.Parent != nil {
+			t.Errorf("nested page parent = %v, want nil", p.Parent)
+		}
+	}
+
+	var nav []string
+	for _, p := range site.NavPages() {
+		nav = append(nav, p.Slug)
+	}
+	if strings.Join(nav, ",") != "about" {
+		t.Errorf("nav pages = %v, want [about]", nav)
+	}
+}
+
+// Verifies that shortcode errors report the source file and line.
+func TestLoadSite_ShortcodeErrorNamesFileAndLine(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, dir, "posts/embed.md", `---
+title: "Embed"
+date: 2026-04-12
+---
+Intro.
+
+{{< unknown >}}`)
+
+	_, err := content.LoadSite(dir)
+	if err == nil {
+		t.Fatal("expected error for unknown shortcode, got nil")
+	}
+	if !strings.Contains(err.Error(), "embed.md:7:") {
+		t.Errorf("error = %q, want file and line", err)
+	}
+}
@@ -0,0 +1,82 @@
+package content
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// textStats counts the words of prose, the lines of code and the images in
+// rendered HTML. Words are only counted outside code blocks, and only if
+// they contain a letter or digit, so heading anchors, footnote back-links
+// and punctuation don't count. Each top-level SVG (a rendered diagram)
+// counts as an image.
+func textStats(s string) (words, codeLines, images int) {
+	for i := 0; i < len(s); {
+		if s[i] != '<' {
+			end := strings.IndexByte(s[i:], '<')
+			if end < 0 {
+				end = len(s) - i
+			}
+			words += countWords(html.UnescapeString(s[i : i+end]))
+			i += end
+			continue
+		}
+
+		if strings.HasPrefix(s[i:], "<!--") {
+			end := strings.Index(s[i:], "-->")
+			if end < 0 {
+				break
+			}
+			i += end + len("-->")
+			continue
+		}
+		end := strings.IndexByte(s[i:], '>')
+		if end < 0 {
+			break
+		}
+		start := i
+		i += end + 1
+
+		name, closing := tagName(s[start:i])
+		if closing {
+			continue
+		}
+		switch name {
+		case "img":
+			images++
+		case "svg":
+			images++
+			i = skipElement(s, i, name)
+		case "pre":
+			body := i
+			i = skipElement(s, i, name)
+			codeLines += countLines(s[body:i])
+		case "script", "style":
+			i = skipElement(s, i, name)
+		}
+	}
+	return words, codeLines, images
+}
+
+// countWords counts the fields of text that contain a letter or digit.
+func countWords(text string) int {
+	n := 0
+	for _, f := range strings.Fields(text) {
+		if strings.IndexFunc(f, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// countLines counts the non-blank lines of text in a code block's HTML.
+func countLines(s string) int {
+	n := 0
+	for line := range strings.Lines(anyTag.ReplaceAllString(s, "")) {
+		if strings.TrimSpace(line) != "" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,214 @@
+package content
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// defaultSummaryWords is the length of an automatic summary when
+// WithSummaryWords isn't used.
+const defaultSummaryWords = 50
+
+// descriptionLength is the most characters in a description derived from a
+// post's summary, which is about what search engines show.
+const descriptionLength = 160
+
+// moreSeparator ends a post's summary. It only counts on a line of its own,
+// where the Markdown renderer passes it through as a block of raw HTML.
+var moreSeparator = regexp.MustCompile(`(?m)^<!--\s*more\s*-->\n?`)
+
+// summarize returns the summary of a post's rendered content: everything
+// before a <!--more--> separator, or else its first words. It also returns
+// the content with the separator removed, and whether there was one.
+func summarize(content string, words int) (summary, rest string, excerpt bool) {
+	if loc := moreSeparator.FindStringIndex(content); loc != nil {
+		return strings.TrimSpace(content[:loc[0]]), content[:loc[0]] + content[loc[1]:], true
+	}
+	return truncateHTML(content, words), content, false
+}
+
+// summarySkip are elements left out of automatic summaries, with everything
+// inside them. Once a summary has some words, it ends at the first of them,
+// so it stops at the end of the introduction rather than running on past a
+// heading or code block.
+var summarySkip = map[string]bool{
+	"figure": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "pre": true, "script": true, "style": true, "sup": true,
+	"svg": true, "table": true,
+}
+
+// voidElements never have a closing tag.
+var voidElements = map[string]bool{
+	"area": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true,
+	"wbr": true,
+}
+
+// truncateHTML returns the first n words of the text in s, keeping the
+// markup around them and closing any elements left open. Images, the
+// elements in summarySkip, and elements left empty without them are dropped.
+func truncateHTML(s string, n int) string {
+	type element struct {
+		name  string
+		start int // offset of the start tag in out
+		empty bool
+	}
+	var (
+		out   []byte
+		open  []element
+		count int
+	)
+	// text writes t, marking the open elements as having content if it has
+	// any words.
+	text := func(t string) {
+		if strings.TrimSpace(t) != "" {
+			for j := range open {
+				open[j].empty = false
+			}
+		}
+		out = append(out, t...)
+	}
+
+scan:
+	for i := 0; i < len(s); {
+		if s[i] != '<' {
+			end := strings.IndexByte(s[i:], '<')
+			if end < 0 {
+				end = len(s) - i
+			}
+			t := s[i : i+end]
+			if cut, ok := cutWords(t, n-count); ok {
+				text(cut + "…")
+				break
+			}
+			count += len(strings.Fields(t))
+			text(t)
+			i += end
+			continue
+		}
+
+		if strings.HasPrefix(s[i:], "<!--") {
+			end := strings.Index(s[i:], "-->")
+			if end < 0 {
+				break
+			}
+			i += end + len("-->")
+			continue
+		}
+		end := strings.IndexByte(s[i:], '>')
+		if end < 0 {
+			break
+		}
+		tag := s[i : i+end+1]
+		i += end + 1
+
+		name, closing := tagName(tag)
+		switch {
+		case closing:
+			if len(open) == 0 || open[len(open)-1].name != name {
+				continue
+			}
+			el := open[len(open)-1]
+			open = open[:len(open)-1]
+			if el.empty {
+				out = out[:el.start]
+			} else {
+				out = append(out, tag...)
+			}
+		case summarySkip[name]:
+			if count > 0 {
+				break scan
+			}
+			i = skipElement(s, i, name)
+		case name == "img":
+		case voidElements[name] || strings.HasSuffix(tag, "/>"):
+			out = append(out, tag...)
+		default:
+			open = append(open, element{name: name, start: len(out), empty: true})
+			out = append(out, tag...)
+		}
+	}
+
+	for j := len(open) - 1; j >= 0; j-- {
+		if open[j].empty {
+			out = out[:open[j].start]
+		} else {
+			out = append(out, "</"+open[j].name+">"...)
+		}
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// cutWords returns text up to the end of its nth word, and whether text has
+// at least that many.
+func cutWords(text string, n int) (string, bool) {
+	inWord := false
+	for i, r := range text {
+		space := strings.ContainsRune(" \t\n\r\f", r)
+		if !space && !inWord {
+			if n == 0 {
+				return strings.TrimRight(text[:i], " \t\n\r\f"), true
+			}
+			n--
+		}
+		inWord = !space
+	}
+	return text, false
+}
+
+// tagName returns the lower-cased name of the tag and whether it closes an
+// element.
+func tagName(tag string) (string, bool) {
+	tag = strings.TrimPrefix(tag, "<")
+	closing := strings.HasPrefix(tag, "/")
+	tag = strings.TrimPrefix(tag, "/")
+	end := strings.IndexAny(tag, " \t\n/>")
+	if end < 0 {
+		end = len(tag)
+	}
+	return strings.ToLower(tag[:end]), closing
+}
+
+// skipElement returns the index just past the end tag matching an element
+// named name whose start tag ends before i.
+func skipElement(s string, i int, name string) int {
+	depth := 1
+	for depth > 0 {
+		next := strings.IndexByte(s[i:], '<')
+		if next < 0 {
+			return len(s)
+		}
+		i += next
+		end := strings.IndexByte(s[i:], '>')
+		if end < 0 {
+			return len(s)
+		}
+		if n, closing := tagName(s[i : i+end+1]); n == name {
+			if closing {
+				depth--
+			} else {
+				depth++
+			}
+		}
+		i += end + 1
+	}
+	return i
+}
+
+var anyTag = regexp.MustCompile(`<[^>]*>`)
+
+// plainText returns the text of an HTML fragment on one line, cut at a word
+// boundary to at most limit characters.
+func plainText(s string, limit int) string {
+	text := strings.Join(strings.Fields(html.UnescapeString(anyTag.ReplaceAllString(s, ""))), " ")
+	if len([]rune(text)) <= limit {
+		return text
+	}
+	text = strings.TrimSuffix(text, "…")
+	cut := string([]rune(text)[:limit-1])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, ".,;:") + "…"
+}
@@ -0,0 +1,282 @@
+package content
+
+import (
+	"cmp"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/integralist/integralist.co.uk/internal/model"
+	"github.com/integralist/integralist.co.uk/internal/parser"
+)
+
+var tagColors = []string{"#D4796A", "#D4A04A", "#6BA397", "#5B7FA5"}
+
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// termFile is a content/<taxonomy>/<slug>.md file, such as
+// content/tags/go.md, which describes the term with that slug.
+type termFile struct {
+	aliases     []string
+	color       string
+	content     string
+	description string
+	name        string // for messages, e.g. tags/go.md
+	parents     []string
+	slug        string
+	title       string
+}
+
+// loadTermFiles reads the term files of tax from dir, by slug.
+func (l *loader) loadTermFiles(tax *model.Taxonomy, dir string) (map[string]*termFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	files := make(map[string]*termFile)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+			continue
+		}
+		name := tax.Key + "/" + e.Name()
+		slug := strings.TrimSuffix(e.Name(), ".md")
+		if model.Slugify(slug) != slug {
+			return nil, fmt.Errorf("%s: file name must be a %s slug, e.g. %s.md", name, tax.Singular, model.Slugify(slug))
+		}
+
+		file := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		meta, body, err := parser.ParseFrontMatter(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", name, err)
+		}
+		html, err := l.converter.Convert(parser.Document{
+			Name:      file,
+			FirstLine: bodyLine(data, body),
+			Body:      body,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		color := getString(meta, "color")
+		if color != "" && !hexColor.MatchString(color) {
+			return nil, fmt.Errorf("%s: color must be #rrggbb, got %q", name, color)
+		}
+		content := strings.TrimSpace(string(html))
+		description := getString(meta, "description")
+		if description == "" {
+			description = plainText(content, descriptionLength)
+		}
+		files[slug] = &termFile{
+			aliases:     getStringSlice(meta, "aliases"),
+			color:       color,
+			content:     content,
+			description: description,
+			name:        name,
+			parents:     getStringSlice(meta, "parents"),
+			slug:        slug,
+			title:       getString(meta, "name"),
+		}
+	}
+	return files, nil
+}
+
+// collectTerms builds the terms of tax from the posts' front matter and the
+// term files. Each post's terms are replaced by the names of the terms they
+// resolve to, after aliases. It returns warnings about names that collide.
+func collectTerms(tax *model.Taxonomy, posts []*model.Post, files map[string]*termFile) ([]*model.Tag, []string, error) {
+	// Aliases resolve to the slug of the term they are merged into.
+	canonical := make(map[string]string)
+	for _, f := range sortedFiles(files) {
+		for _, alias := range f.aliases {
+			slug := model.Slugify(alias)
+			if other, ok := files[slug]; ok && slug != f.slug {
+				return nil, nil, fmt.Errorf("%s: alias %q is a %s of its own in %s", f.name, alias, tax.Singular, other.name)
+			}
+			if prev, ok := canonical[slug]; ok && prev != f.slug {
+				return nil, nil, fmt.Errorf("%s: alias %q is also an alias of %s", f.name, alias, files[prev].name)
+			}
+			canonical[slug] = f.slug
+		}
+	}
+	resolve := func(name string) string {
+		slug := model.Slugify(name)
+		if c, ok := canonical[slug]; ok {
+			return c
+		}
+		return slug
+	}
+
+	bySlug := make(map[string]*model.Tag)
+	add := func(slug, name string) *model.Tag {
+		tag := &model.Tag{Name: name, Slug: slug, URL: tax.URL() + slug + "/"}
+		if f, ok := files[slug]; ok {
+			tag.Aliases = f.aliases
+			tag.Color = f.color
+			tag.Content = template.HTML(f.content)
+			tag.Description = f.description
+			tag.Name = cmp.Or(f.title, name)
+		}
+		bySlug[slug] = tag
+		return tag
+	}
+
+	// Terms named differently in posts but with the same slug are merged
+	// under the first name seen, unless their file gives a name.
+	spellings := make(map[string][]string)
+	for _, p := range posts {
+		for _, name := range tax.PostTerms(p) {
+			slug := model.Slugify(name)
+			if !slices.Contains(spellings[slug], name) {
+				spellings[slug] = append(spellings[slug], name)
+			}
+			if _, ok := bySlug[resolve(name)]; !ok {
+				add(resolve(name), name)
+			}
+		}
+	}
+	for _, f := range sortedFiles(files) {
+		if _, ok := bySlug[f.slug]; !ok {
+			add(f.slug, cmp.Or(f.title, f.slug))
+		}
+	}
+
+	var warnings []string
+	for _, names := range spellings {
+		if len(names) < 2 {
+			continue
+		}
+		target := resolve(names[0])
+		if f, ok := files[target]; ok && f.title != "" {
+			continue
+		}
+		quoted := make([]string, len(names))
+		for i, n := range names {
+			quoted[i] = fmt.Sprintf("%q", n)
+		}
+		warnings = append(warnings, fmt.Sprintf("%s %s share the URL %s%s/; using %q (set name in content/%s/%s.md to choose)",
+			tax.Key, strings.Join(quoted, ", "), tax.URL(), target, bySlug[target].Name, tax.Key, target))
+	}
+
+	for _, f := range sortedFiles(files) {
+		tag := bySlug[f.slug]
+		for _, name := range f.parents {
+			parent, ok := bySlug[resolve(name)]
+			if !ok {
+				return nil, nil, fmt.Errorf("%s: unknown parent %s %q", f.name, tax.Singular, name)
+			}
+			if !slices.Contains(tag.Parents, parent) {
+				tag.Parents = append(tag.Parents, parent)
+				parent.Children = append(parent.Children, tag)
+			}
+		}
+	}
+	for _, f := range sortedFiles(files) {
+		if tag := bySlug[f.slug]; ancestorOf(tag, tag) {
+			return nil, nil, fmt.Errorf("%s: %s %q is its own ancestor", f.name, tax.Singular, tag.Name)
+		}
+	}
+
+	// Posts are listed under their terms and all of their ancestors. posts
+	// is sorted, so each term's posts are too.
+	for _, p := range posts {
+		var names []string
+		var seen []*model.Tag
+		for _, name := range tax.PostTerms(p) {
+			tag := bySlug[resolve(name)]
+			if !slices.Contains(names, tag.Name) {
+				names = append(names, tag.Name)
+			}
+			for _, t := range append([]*model.Tag{tag}, ancestors(tag)...) {
+				if !slices.Contains(seen, t) {
+					seen = append(seen, t)
+					t.Posts = append(t.Posts, p)
+				}
+			}
+		}
+		switch {
+		case names == nil:
+		case tax.Key == model.TagsKey:
+			p.Tags = names
+		default:
+			p.Terms[tax.Key] = names
+		}
+	}
+
+	tags := make([]*model.Tag, 0, len(bySlug))
+	for _, t := range bySlug {
+		if len(t.Posts) == 0 {
+			warnings = append(warnings, fmt.Sprintf("%s/%s.md: no posts have this %s", tax.Key, t.Slug, tax.Singular))
+			continue
+		}
+		t.Children = slices.DeleteFunc(t.Children, func(c *model.Tag) bool { return len(c.Posts) == 0 })
+		sort.Slice(t.Children, func(i, j int) bool { return t.Children[i].Name < t.Children[j].Name })
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+
+	for i, t := range tags {
+		if t.Color == "" {
+			t.Color = tagColors[i%len(tagColors)]
+		}
+	}
+	sort.Strings(warnings)
+
+	return tags, warnings, nil
+}
+
+// getTerms reads a post's terms in a taxonomy other than tags, which may be
+// a list or, as is usual for a series, a single name.
+func getTerms(m map[string]any, key string) []string {
+	if s, ok := m[key].(string); ok && s != "" {
+		return []string{s}
+	}
+	return getStringSlice(m, key)
+}
+
+// sortedFiles returns files ordered by slug, so errors are deterministic.
+func sortedFiles(files map[string]*termFile) []*termFile {
+	sorted := make([]*termFile, 0, len(files))
+	for _, f := range files {
+		sorted = append(sorted, f)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].slug < sorted[j].slug })
+	return sorted
+}
+
+// ancestors returns every term above tag, nearest first. It stops at a term
+// it has already seen, so it ends even if the parents form a cycle.
+func ancestors(tag *model.Tag) []*model.Tag {
+	var found []*model.Tag
+	queue := slices.Clone(tag.Parents)
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		if slices.Contains(found, t) {
+			continue
+		}
+		found = append(found, t)
+		queue = append(queue, t.Parents...)
+	}
+	return found
+}
+
+// ancestorOf reports whether a is an ancestor of tag.
+func ancestorOf(a, tag *model.Tag) bool {
+	return slices.Contains(ancestors(tag), a)
+}
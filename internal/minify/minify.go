@@ -0,0 +1,314 @@
+// Package minify strips insignificant whitespace and comments from the HTML
+// and CSS the build writes. It is deliberately conservative: content whose
+// whitespace matters, such as <pre> blocks, is passed through untouched.
+package minify
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// blockTags are elements whose surrounding whitespace never renders, so it
+// can be dropped rather than collapsed to a single space.
+var blockTags = map[string]bool{
+	"article": true, "aside": true, "blockquote": true, "body": true,
+	"br": true, "caption": true, "dd": true, "details": true, "div": true,
+	"dl": true, "dt": true, "figcaption": true, "figure": true,
+	"footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "head": true, "header": true,
+	"hr": true, "html": true, "li": true, "link": true, "main": true,
+	"meta": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"script": true, "section": true, "style": true, "summary": true,
+	"table": true, "tbody": true, "td": true, "tfoot": true, "th": true,
+	"thead": true, "title": true, "tr": true, "ul": true, "!doctype": true,
+}
+
+// rawTags are elements whose content is not HTML text. pre and textarea are
+// copied exactly; style and JSON-LD scripts are minified as CSS and JSON.
+var rawTags = map[string]bool{
+	"pre":      true,
+	"script":   true,
+	"style":    true,
+	"textarea": true,
+}
+
+// HTML minifies an HTML document.
+func HTML(src []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(src))
+
+	prevTag := "" // name of the tag before the pending text, "" at the start
+	textStart := 0
+	i := 0
+	for i < len(src) {
+		if src[i] != '<' {
+			i++
+			continue
+		}
+
+		if bytes.HasPrefix(src[i:], []byte("<!--")) {
+			end := bytes.Index(src[i+4:], []byte("-->"))
+			if end < 0 {
+				break
+			}
+			end += i + 4 + 3
+			if bytes.HasPrefix(src[i:], []byte("<!--[if")) {
+				i = end // conditional comments are kept with the text around them
+				continue
+			}
+			writeText(&out, src[textStart:i], prevTag, "")
+			textStart, i = end, end
+			continue
+		}
+
+		end := tagEnd(src, i)
+		name := tagName(src[i:end])
+		if name == "" {
+			i++ // a bare "<" in text
+			continue
+		}
+
+		writeText(&out, src[textStart:i], prevTag, name)
+		tag := src[i:end]
+		out.Write(tag)
+		prevTag, textStart, i = name, end, end
+
+		if !rawTags[name] || bytes.HasSuffix(tag, []byte("/>")) {
+			continue
+		}
+		close := indexFold(src[end:], []byte("</"+name))
+		if close < 0 {
+			break
+		}
+		content := src[end : end+close]
+		switch {
+		case name == "style":
+			out.Write(CSS(content))
+		case name == "script" && isJSONLD(tag):
+			out.Write(compactJSON(content))
+		default:
+			out.Write(content)
+		}
+		textStart, i = end+close, end+close
+	}
+	writeText(&out, src[textStart:], prevTag, "")
+	return out.Bytes()
+}
+
+// writeText writes the text between two tags with runs of whitespace
+// collapsed, dropping whitespace next to block-level tags entirely.
+func writeText(out *bytes.Buffer, text []byte, prevTag, nextTag string) {
+	collapsed := collapseSpace(text)
+	if prevTag == "" || blockTags[strings.TrimPrefix(prevTag, "/")] {
+		collapsed = bytes.TrimLeft(collapsed, " ")
+	}
+	if nextTag == "" || blockTags[strings.TrimPrefix(nextTag, "/")] {
+		collapsed = bytes.TrimRight(collapsed, " ")
+	}
+	out.Write(collapsed)
+}
+
+func collapseSpace(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	space := false
+	for _, c := range b {
+		if isSpace(c) {
+			space = true
+			continue
+		}
+		if space {
+			out = append(out, ' ')
+			space = false
+		}
+		out = append(out, c)
+	}
+	if space {
+		out = append(out, ' ')
+	}
+	return out
+}
+
+// tagEnd returns the index just past the tag starting at src[i], skipping
+// any ">" inside quoted attribute values.
+func tagEnd(src []byte, i int) int {
+	var quote byte
+	for j := i + 1; j < len(src); j++ {
+		c := src[j]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return j + 1
+		}
+	}
+	return len(src)
+}
+
+// tagName returns the lower-cased name of tag, prefixed with "/" for a
+// closing tag, or "" if tag is not a tag at all.
+func tagName(tag []byte) string {
+	rest := tag[1:]
+	prefix := ""
+	if len(rest) > 0 && rest[0] == '/' {
+		prefix, rest = "/", rest[1:]
+	}
+	n := 0
+	for n < len(rest) && (isAlnum(rest[n]) || rest[n] == '-' || (n == 0 && rest[n] == '!')) {
+		n++
+	}
+	if n == 0 {
+		return ""
+	}
+	return prefix + strings.ToLower(string(rest[:n]))
+}
+
+func isJSONLD(tag []byte) bool {
+	return bytes.Contains(bytes.ToLower(tag), []byte("application/ld+json"))
+}
+
+func compactJSON(src []byte) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, src); err != nil {
+		return src
+	}
+	return buf.Bytes()
+}
+
+func indexFold(s, sep []byte) int {
+	return bytes.Index(bytes.ToLower(s), bytes.ToLower(sep))
+}
+
+// cssPunct are characters that whitespace around can always be removed
+// from. ":" is handled separately: a space before it is significant in
+// selectors such as "div :first-child".
+const cssPunct = "{};,>"
+
+// CSS minifies a stylesheet.
+func CSS(src []byte) []byte {
+	out := make([]byte, 0, len(src))
+	space := false
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := bytes.Index(src[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(src)
+				continue
+			}
+			i += 2 + end + 1
+			space = true
+			continue
+		case isSpace(c):
+			space = true
+			continue
+		}
+
+		if space && len(out) > 0 && !strings.ContainsRune(cssPunct+":", rune(out[len(out)-1])) && !strings.ContainsRune(cssPunct, rune(c)) {
+			out = append(out, ' ')
+		}
+		space = false
+
+		if c == '}' && len(out) > 0 && out[len(out)-1] == ';' {
+			out = out[:len(out)-1]
+		}
+
+		if c == '"' || c == '\'' {
+			end := stringEnd(src, i)
+			out = append(out, src[i:end]...)
+			i = end - 1
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// stringEnd returns the index just past the quoted string starting at
+// src[i].
+func stringEnd(src []byte, i int) int {
+	quote := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1
+		}
+	}
+	return len(src)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// Result records the total size of the files of one type before and after
+// minifying.
+type Result struct {
+	Files  int
+	Before int64
+	After  int64
+}
+
+// Saved returns the number of bytes minifying removed.
+func (r Result) Saved() int64 {
+	return r.Before - r.After
+}
+
+// minifiers are the minifiers for each file extension Dir supports.
+var minifiers = map[string]func([]byte) []byte{
+	".css":  CSS,
+	".html": HTML,
+}
+
+// Dir minifies every file under dir with one of exts (".html" or ".css") in
+// place and returns the results keyed by file type ("html" or "css").
+func Dir(dir string, exts ...string) (map[string]Result, error) {
+
+	results := make(map[string]Result)
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := filepath.Ext(file)
+		minify, ok := minifiers[ext]
+		if d.IsDir() || !ok || !slices.Contains(exts, ext) {
+			return nil
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		min := minify(data)
+		if err := os.WriteFile(file, min, 0o644); err != nil {
+			return err
+		}
+
+		kind := strings.TrimPrefix(ext, ".")
+		r := results[kind]
+		r.Files++
+		r.Before += int64(len(data))
+		r.After += int64(len(min))
+		results[kind] = r
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,117 @@
+package minify_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/integralist/integralist.co.uk/internal/minify"
+)
+
+func TestHTML(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  string
+	}{
+		"block whitespace": {
+			"<!DOCTYPE html>\n<html>\n  <body>\n    <p>Hello   <em>big</em>\n  world</p>\n  </body>\n</html>\n",
+			"<!DOCTYPE html><html><body><p>Hello <em>big</em> world</p></body></html>",
+		},
+		"comments": {
+			"<p>a</p>\n<!-- note -->\n<p>b</p>",
+			"<p>a</p><p>b</p>",
+		},
+		"pre preserved": {
+			"<div>\n  <pre><code>func main() {\n\tfmt.Println(\"hi\")\n}\n</code></pre>\n</div>",
+			"<div><pre><code>func main() {\n\tfmt.Println(\"hi\")\n}\n</code></pre></div>",
+		},
+		"attributes untouched": {
+			`<a title="a  >  b"  href="/x">link</a>`,
+			`<a title="a  >  b"  href="/x">link</a>`,
+		},
+		"inline spacing kept": {
+			"<a href=\"/a\">a</a>\n<a href=\"/b\">b</a>",
+			"<a href=\"/a\">a</a> <a href=\"/b\">b</a>",
+		},
+		"json-ld": {
+			"<script type=\"application/ld+json\">\n{\n  \"@type\": \"BlogPosting\",\n  \"headline\": \"A  b\"\n}\n</script>",
+			"<script type=\"application/ld+json\">{\"@type\":\"BlogPosting\",\"headline\":\"A  b\"}</script>",
+		},
+		"other scripts untouched": {
+			"<script>\n  if (a < b) {  go() }\n</script>",
+			"<script>\n  if (a < b) {  go() }\n</script>",
+		},
+		"style": {
+			"<style>\n  p {\n    color: red;\n  }\n</style>",
+			"<style>p{color:red}</style>",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := string(minify.HTML([]byte(tc.input))); got != tc.want {
+				t.Errorf("got  %q\nwant %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCSS(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  string
+	}{
+		"declarations": {
+			"/* base */\nbody {\n  margin: 0;\n  font-family: \"SF Mono\", monospace;\n}\n",
+			"body{margin:0;font-family:\"SF Mono\",monospace}",
+		},
+		"descendant pseudo-class": {
+			"div :first-child { color: red }",
+			"div :first-child{color:red}",
+		},
+		"calc keeps operators": {
+			"a { width: calc(100% - 2rem); }",
+			"a{width:calc(100% - 2rem)}",
+		},
+		"strings untouched": {
+			"a::after { content: \"/* not a comment */  ;\"; }",
+			"a::after{content:\"/* not a comment */  ;\"}",
+		},
+		"media query": {
+			"@media (max-width: 600px) {\n  .a > .b { display: none; }\n}",
+			"@media (max-width:600px){.a>.b{display:none}}",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := string(minify.CSS([]byte(tc.input))); got != tc.want {
+				t.Errorf("got  %q\nwant %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "css"), 0o755)
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("<p>\n  hi\n</p>\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "css", "style.css"), []byte("a {\n  color: red;\n}\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "index.md"), []byte("# Title\n\n\nBody\n"), 0o644)
+
+	results, err := minify.Dir(dir, ".html", ".css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results["html"]; r.Files != 1 || r.Saved() != 5 {
+		t.Errorf("html result = %+v, want 1 file and 5 bytes saved", r)
+	}
+	if r := results["css"]; r.Files != 1 || r.After != int64(len("a{color:red}")) {
+		t.Errorf("css result = %+v, want 1 file minified", r)
+	}
+	if _, ok := results["md"]; ok {
+		t.Error("markdown files should not be minified")
+	}
+	md, _ := os.ReadFile(filepath.Join(dir, "index.md"))
+	if string(md) != "# Title\n\n\nBody\n" {
+		t.Errorf("index.md changed: %q", md)
+	}
+}
@@ -9,47 +9,194 @@ import (
 )
 
 type Post struct {
-	Author        string
-	Content       template.HTML
-	Date          time.Time
-	Description   string
+	Author string
+	// CardImage is the URL of the post's generated social card, used in
+	// place of Image when that is empty.
+	CardImage string
+	// CodeLines counts the lines in the post's code blocks.
+	CodeLines int
+	Content   template.HTML
+	CSS       []string
+	Date      time.Time
+	// Description is the description front matter key, or else the start of
+	// Summary as plain text.
+	Description string
+	// Excerpt reports whether Summary was marked by a <!--more--> separator
+	// rather than taken from the first words of the post.
+	Excerpt       bool
 	Image         string
 	ImagePosition string
-	JS            []string
-	Keywords      []string
-	MarkdownURL   string
-	Slug          string
-	SourceMD      []byte
-	Tags          []string
-	Title         string
-	URL           string
+	// Images counts the post's images and diagrams.
+	Images   int
+	JS       []string
+	Keywords []string
+	// LastMod is when the post last changed: Updated if set, otherwise the
+	// source file's last commit or modification time, and never before Date.
+	LastMod     time.Time
+	MarkdownURL string
+	// ReadingTime is the estimated minutes to read the post.
+	ReadingTime int
+	Slug        string
+	SourceMD    []byte
+	// Summary is the HTML before a <!--more--> separator, or the post's first
+	// words.
+	Summary template.HTML
+	Tags    []string
+	// Terms holds the post's terms in each taxonomy other than tags, by
+	// taxonomy key.
+	Terms map[string][]string
+	Title string
+	// Updated is set from the updated (or lastmod) front matter key.
+	Updated time.Time
+	URL     string
+	// WordCount counts the words in the post's rendered text, leaving out
+	// code blocks and markup.
+	WordCount int
+}
+
+// ReadingSpeed is how long a reader spends on each part of a post.
+type ReadingSpeed struct {
+	WordsPerMinute     int
+	SecondsPerCodeLine int
+	SecondsPerImage    int
 }
 
-func (p Post) ReadingTime() int {
-	words := len(strings.Fields(string(p.SourceMD)))
-	return int(math.Ceil(float64(words) / 200))
+// DefaultReadingSpeed is used when no other speed is configured.
+var DefaultReadingSpeed = ReadingSpeed{
+	WordsPerMinute:     200,
+	SecondsPerCodeLine: 2,
+	SecondsPerImage:    12,
+}
+
+// Minutes returns the time to read words of prose, codeLines lines of code
+// and images images, rounded up to a whole minute and at least one.
+func (s ReadingSpeed) Minutes(words, codeLines, images int) int {
+	seconds := float64(words)*60/float64(s.WordsPerMinute) +
+		float64(codeLines*s.SecondsPerCodeLine) +
+		float64(images*s.SecondsPerImage)
+	return max(1, int(math.Ceil(seconds/60)))
 }
 
 type Page struct {
+	Children      []*Page
 	Content       template.HTML
 	Description   string
 	Image         string
 	ImagePosition string
 	Keywords      []string
-	MarkdownURL   string
-	NavOrder      int
-	Slug          string
-	SourceMD      []byte
-	Title         string
-	URL           string
+	// LastMod is Updated if set, otherwise the source file's last commit or
+	// modification time.
+	LastMod     time.Time
+	MarkdownURL string
+	NavExclude  bool
+	NavOrder    int
+	Parent      *Page
+	// Person is who a ProfilePage is about.
+	Person *Person
+	// Schema is the schema.org type the page describes itself as in its
+	// structured data, e.g. "ProfilePage". Empty for an ordinary page.
+	Schema   string
+	Section  bool
+	Slug     string
+	SourceMD []byte
+	Title    string
+	// Updated is set from the updated (or lastmod) front matter key.
+	Updated time.Time
+	URL     string
+}
+
+// Person describes someone in structured data.
+type Person struct {
+	Name     string
+	JobTitle string
+	// SameAs lists the person's profiles on other sites.
+	SameAs []string
 }
 
+// Breadcrumb is a single step in the trail from the site root to a page.
+type Breadcrumb struct {
+	Title string
+	URL   string
+}
+
+// Breadcrumbs returns the trail of ancestor pages, root first, ending with
+// the page itself.
+func (p *Page) Breadcrumbs() []Breadcrumb {
+	var crumbs []Breadcrumb
+	for cur := p; cur != nil; cur = cur.Parent {
+		crumbs = append([]Breadcrumb{{Title: cur.Title, URL: cur.URL}}, crumbs...)
+	}
+	return crumbs
+}
+
+// Tag is a tag, or more generally a term in any taxonomy.
 type Tag struct {
-	Name  string
-	Slug  string
+	// Aliases are other names that are merged into this tag. Each has its
+	// own URL, redirected to the tag's.
+	Aliases []string
+	// Children are the tags that list this one as a parent.
+	Children []*Tag
+	Color    string
+	// Content is the rendered body of the tag's content/tags/<slug>.md file.
+	Content template.HTML
+	// Description is the description key of the tag's file, or else the
+	// start of Content as plain text.
+	Description string
+	Name        string
+	// Parents are the broader tags this one belongs to.
+	Parents []*Tag
+	// Posts are the posts with this tag or any of its descendants, newest
+	// first.
 	Posts []*Post
+	Slug  string
 	URL   string
-	Color string
+}
+
+// LastMod returns the latest LastMod of the tag's posts.
+func (t *Tag) LastMod() time.Time {
+	return lastMod(t.Posts)
+}
+
+// Taxonomy groups posts by the terms listed in one of their front matter
+// keys. Tags are the built-in taxonomy; others, such as series, are
+// configured per site.
+type Taxonomy struct {
+	// Key is the front matter key and the URL path, e.g. "series".
+	Key string
+	// Singular names one term in headings, e.g. "series" or "category".
+	Singular string
+	Terms    []*Tag
+	// Title heads the index page, e.g. "Series".
+	Title string
+}
+
+// TagsKey is the key of the built-in tags taxonomy.
+const TagsKey = "tags"
+
+// URL returns the URL of the taxonomy's index page.
+func (t *Taxonomy) URL() string {
+	return "/" + t.Key + "/"
+}
+
+// Heading introduces a term's name on its page, e.g. "Posts tagged".
+func (t *Taxonomy) Heading() string {
+	if t.Key == TagsKey {
+		return "Posts tagged"
+	}
+	return "Posts in " + t.Singular
+}
+
+// TermTitle titles the page of term, e.g. Posts tagged "go".
+func (t *Taxonomy) TermTitle(term *Tag) string {
+	return t.Heading() + " \"" + term.Name + "\""
+}
+
+// PostTerms returns the names of the post's terms in the taxonomy.
+func (t *Taxonomy) PostTerms(p *Post) []string {
+	if t.Key == TagsKey {
+		return p.Tags
+	}
+	return p.Terms[t.Key]
 }
 
 type Site struct {
@@ -57,6 +204,41 @@ type Site struct {
 	Posts   []*Post
 	Pages   []*Page
 	Tags    []*Tag
+	// Taxonomies are all the site's taxonomies, tags first. The terms of the
+	// first are Tags.
+	Taxonomies []*Taxonomy
+	// Warnings are problems with the content that don't stop the build,
+	// such as tag names that collide.
+	Warnings []string
+}
+
+// LastMod returns the latest LastMod of all posts, which is when the home
+// page and post listings last changed.
+func (s *Site) LastMod() time.Time {
+	return lastMod(s.Posts)
+}
+
+func lastMod(posts []*Post) time.Time {
+	var t time.Time
+	for _, p := range posts {
+		if p.LastMod.After(t) {
+			t = p.LastMod
+		}
+	}
+	return t
+}
+
+// NavPages returns the top-level pages that appear in the site navigation.
+// A nested page is never top-level, even when its directory has no section
+// index to be its parent.
+func (s *Site) NavPages() []*Page {
+	var pages []*Page
+	for _, p := range s.Pages {
+		if p.Parent == nil && !p.NavExclude && !strings.Contains(p.Slug, "/") {
+			pages = append(pages, p)
+		}
+	}
+	return pages
 }
 
 var (
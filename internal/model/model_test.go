@@ -6,30 +6,26 @@ import (
 	"github.com/integralist/integralist.co.uk/internal/model"
 )
 
-func TestPost_ReadingTime(t *testing.T) {
+func TestReadingSpeed_Minutes(t *testing.T) {
 	testCases := []struct {
-		name  string
-		words int
-		want  int
+		name                     string
+		words, codeLines, images int
+		want                     int
 	}{
-		{"short post rounds up to 1 min", 50, 1},
-		{"exactly 200 words", 200, 1},
-		{"201 words rounds up to 2 min", 201, 2},
-		{"long post", 1000, 5},
+		{"empty post is still 1 min", 0, 0, 0, 1},
+		{"short post rounds up to 1 min", 50, 0, 0, 1},
+		{"exactly 200 words", 200, 0, 0, 1},
+		{"201 words rounds up to 2 min", 201, 0, 0, 2},
+		{"long post", 1000, 0, 0, 5},
+		{"code lines add 2s each", 200, 30, 0, 2},
+		{"images add 12s each", 100, 0, 5, 2},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			body := make([]byte, 0, tc.words*6)
-			for i := range tc.words {
-				if i > 0 {
-					body = append(body, ' ')
-				}
-				body = append(body, []byte("word")...)
-			}
-			p := model.Post{SourceMD: body}
-			if got := p.ReadingTime(); got != tc.want {
-				t.Errorf("ReadingTime() = %d, want %d", got, tc.want)
+			got := model.DefaultReadingSpeed.Minutes(tc.words, tc.codeLines, tc.images)
+			if got != tc.want {
+				t.Errorf("Minutes(%d, %d, %d) = %d, want %d", tc.words, tc.codeLines, tc.images, got, tc.want)
 			}
 		})
 	}
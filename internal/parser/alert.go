@@ -0,0 +1,259 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/gomarkdown/markdown/ast"
+)
+
+// alertMarker matches the [!TYPE] marker that opens an alert and an optional
+// +/- fold marker. The rest of the line is an optional custom title.
+var alertMarker = regexp.MustCompile(`^\[!([A-Za-z]+)\]([+-]?)[ \t]*`)
+
+// alertIcons are the built-in alert types and their title icons. More can be
+// registered with Options.Alerts.
+var alertIcons = map[string]string{
+	"ABSTRACT":  "\U0001F4CB",
+	"ATTENTION": "⚠️",
+	"BUG":       "\U0001F41B",
+	"CAUTION":   "\U0001F525",
+	"CHECK":     "✅",
+	"CITE":      "\U0001F4AC",
+	"DANGER":    "\U0001F6A8",
+	"DONE":      "✅",
+	"ERROR":     "❌",
+	"EXAMPLE":   "\U0001F4DD",
+	"FAIL":      "❌",
+	"FAILURE":   "❌",
+	"FAQ":       "❓",
+	"HELP":      "❓",
+	"HINT":      "\U0001F4A1",
+	"IMPORTANT": "❗",
+	"INFO":      "ℹ️",
+	"MISSING":   "❌",
+	"NOTE":      "ℹ️",
+	"QUESTION":  "❓",
+	"QUOTE":     "\U0001F4AC",
+	"SUCCESS":   "✅",
+	"SUMMARY":   "\U0001F4CB",
+	"TIP":       "\U0001F4A1",
+	"TLDR":      "\U0001F4CB",
+	"TODO":      "☑️",
+	"WARNING":   "⚠️",
+}
+
+// alert is a blockquote recognised as a GFM-style alert. It replaces the
+// blockquote in the AST so the renderer hook can emit alert markup while the
+// body renders like any other block content. Its first child is the
+// alertTitle.
+type alert struct {
+	ast.Container
+
+	kind string // upper-case alert type, e.g. "NOTE"
+	fold string // "" for a static alert, "+" open or "-" closed for a foldable one
+}
+
+// alertTitle holds the inline content of an alert's title, so that markup
+// in a custom title renders like any other inline content.
+type alertTitle struct {
+	ast.Container
+
+	icon string
+	fold string
+}
+
+// transformAlerts replaces alert blockquotes under node with alert nodes.
+//
+// Blockquotes separated only by blank lines are merged by the parser, so a
+// single blockquote may hold several alerts: every paragraph that starts with
+// a marker opens a new alert, and the blocks that follow it (paragraphs,
+// lists, code) form its body.
+func transformAlerts(node ast.Node, icons map[string]string) {
+	children := node.GetChildren()
+	if len(children) == 0 {
+		return
+	}
+
+	var out []ast.Node
+	for _, child := range children {
+		transformAlerts(child, icons)
+		bq, ok := child.(*ast.BlockQuote)
+		if !ok {
+			out = append(out, child)
+			continue
+		}
+		out = append(out, splitAlerts(bq, icons)...)
+	}
+
+	for _, child := range out {
+		child.SetParent(node)
+	}
+	node.SetChildren(out)
+}
+
+// splitAlerts returns the nodes that should replace bq: bq itself when it
+// holds no alerts, or one alert per marker (preceded by a blockquote for any
+// leading non-alert content).
+func splitAlerts(bq *ast.BlockQuote, icons map[string]string) []ast.Node {
+	var (
+		out     []ast.Node
+		current ast.Node
+		leading []ast.Node
+	)
+	for _, child := range bq.Children {
+		if a := newAlert(child, icons); a != nil {
+			current = a
+			out = append(out, a)
+			if para := child.(*ast.Paragraph); len(para.Children) > 0 {
+				appendChild(a, para)
+			}
+			continue
+		}
+		if current == nil {
+			leading = append(leading, child)
+			continue
+		}
+		appendChild(current, child)
+	}
+
+	if len(out) == 0 {
+		return []ast.Node{bq}
+	}
+	if len(leading) > 0 {
+		bq.Children = leading
+		out = append([]ast.Node{bq}, out...)
+	}
+	return out
+}
+
+// newAlert returns an alert if node is a paragraph opening with a known
+// [!TYPE] marker. The marker, and the custom title after it up to the end of
+// the line, are moved out of the paragraph, which is emptied if nothing else
+// remains.
+func newAlert(node ast.Node, icons map[string]string) *alert {
+	para, ok := node.(*ast.Paragraph)
+	if !ok || len(para.Children) == 0 {
+		return nil
+	}
+	text, ok := para.Children[0].(*ast.Text)
+	if !ok {
+		return nil
+	}
+	m := alertMarker.FindSubmatch(text.Literal)
+	if m == nil {
+		return nil
+	}
+	kind := strings.ToUpper(string(m[1]))
+	icon, ok := icons[kind]
+	if !ok {
+		return nil
+	}
+	text.Literal = text.Literal[len(m[0]):]
+
+	a := &alert{kind: kind, fold: string(m[2])}
+	title := &alertTitle{icon: icon, fold: a.fold}
+	appendChild(a, title)
+
+	// The title is every inline node up to the first line break, which is
+	// either a newline within a text node or a hard break.
+	rest := para.Children
+	for len(rest) > 0 {
+		child := rest[0]
+		if _, ok := child.(*ast.Hardbreak); ok {
+			rest = rest[1:]
+			break
+		}
+		if t, ok := child.(*ast.Text); ok {
+			if line, after, found := bytes.Cut(t.Literal, []byte("\n")); found {
+				appendChild(title, &ast.Text{Leaf: ast.Leaf{Literal: line}})
+				t.Literal = after
+				if len(after) == 0 {
+					rest = rest[1:]
+				}
+				break
+			}
+		}
+		appendChild(title, child)
+		rest = rest[1:]
+	}
+	para.Children = rest
+	trimTitle(title, kind)
+
+	return a
+}
+
+// trimTitle drops the whitespace around title's content, and falls back to
+// kind when there is none.
+func trimTitle(title *alertTitle, kind string) {
+	blank := func(n ast.Node) bool {
+		t, ok := n.(*ast.Text)
+		return ok && len(bytes.TrimSpace(t.Literal)) == 0
+	}
+	children := title.Children
+	for len(children) > 0 && blank(children[0]) {
+		children = children[1:]
+	}
+	for len(children) > 0 && blank(children[len(children)-1]) {
+		children = children[:len(children)-1]
+	}
+	if len(children) == 0 {
+		text := &ast.Text{Leaf: ast.Leaf{Literal: []byte(kind)}}
+		text.SetParent(title)
+		children = []ast.Node{text}
+	}
+	if t, ok := children[0].(*ast.Text); ok {
+		t.Literal = bytes.TrimLeft(t.Literal, " \t")
+	}
+	if t, ok := children[len(children)-1].(*ast.Text); ok {
+		t.Literal = bytes.TrimRight(t.Literal, " \t")
+	}
+	title.Children = children
+}
+
+func appendChild(parent, child ast.Node) {
+	child.SetParent(parent)
+	parent.SetChildren(append(parent.GetChildren(), child))
+}
+
+// renderAlert writes a static alert as a <div>, and a foldable one as
+// <details>/<summary> so it collapses without JavaScript.
+func renderAlert(w io.Writer, a *alert, entering bool) (ast.WalkStatus, bool) {
+	class := "alert alert-" + strings.ToLower(a.kind)
+
+	switch {
+	case !entering && a.fold == "":
+		io.WriteString(w, "</div>\n")
+	case !entering:
+		io.WriteString(w, "</details>\n")
+	case a.fold == "":
+		io.WriteString(w, `<div class="`+class+`">`)
+	default:
+		open := ""
+		if a.fold == "+" {
+			open = " open"
+		}
+		io.WriteString(w, `<details class="`+class+`"`+open+`>`)
+	}
+	return ast.GoToNext, true
+}
+
+// renderAlertTitle writes the title of an alert, as the <summary> of a
+// foldable one, with the alert's icon before it.
+func renderAlertTitle(w io.Writer, t *alertTitle, entering bool) (ast.WalkStatus, bool) {
+	tag := "p"
+	if t.fold != "" {
+		tag = "summary"
+	}
+	if !entering {
+		io.WriteString(w, "</"+tag+">")
+		return ast.GoToNext, true
+	}
+	io.WriteString(w, "<"+tag+` class="alert-title">`)
+	if t.icon != "" {
+		io.WriteString(w, t.icon+" ")
+	}
+	return ast.GoToNext, true
+}
@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gomarkdown/markdown/ast"
+)
+
+// DiagramRenderer renders the source of a diagram code block, such as a
+// ```mermaid fence, to an SVG document.
+type DiagramRenderer interface {
+	RenderDiagram(lang string, src []byte) ([]byte, error)
+}
+
+// CommandRenderer renders diagrams by running a local command, which reads
+// the diagram source on stdin and writes SVG to stdout.
+type CommandRenderer struct {
+	// Command is the program and its arguments, e.g.
+	// ["mmdc", "--input", "-", "--output", "-", "--outputFormat", "svg"].
+	Command []string
+	// Timeout bounds each run. Zero means no limit.
+	Timeout time.Duration
+}
+
+// RenderDiagram implements DiagramRenderer.
+func (r *CommandRenderer) RenderDiagram(lang string, src []byte) ([]byte, error) {
+	if len(r.Command) == 0 {
+		return nil, errors.New("no diagram command configured")
+	}
+
+	ctx := context.Background()
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.Command[0], r.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(src)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", r.Command[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", r.Command[0], err)
+	}
+
+	svg := stdout.Bytes()
+	if i := bytes.Index(svg, []byte("<svg")); i >= 0 {
+		svg = svg[i:] // drop any XML prolog so the SVG can be inlined
+	} else {
+		return nil, fmt.Errorf("%s: output is not SVG", r.Command[0])
+	}
+	return bytes.TrimSpace(svg), nil
+}
+
+// CachedRenderer stores rendered diagrams in Dir, keyed by a hash of Key and
+// their language and source, so unchanged diagrams are not re-rendered on
+// every build.
+type CachedRenderer struct {
+	Renderer DiagramRenderer
+	Dir      string
+	// Key identifies how Renderer is configured, such as its command line,
+	// so that changing it renders every diagram afresh.
+	Key string
+}
+
+// RenderDiagram implements DiagramRenderer.
+func (r *CachedRenderer) RenderDiagram(lang string, src []byte) ([]byte, error) {
+	sum := sha256.Sum256(append([]byte(r.Key+"\x00"+lang+"\x00"), src...))
+	file := filepath.Join(r.Dir, hex.EncodeToString(sum[:])+".svg")
+
+	if svg, err := os.ReadFile(file); err == nil {
+		return svg, nil
+	}
+
+	svg, err := r.Renderer.RenderDiagram(lang, src)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(r.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating diagram cache: %w", err)
+	}
+	if err := os.WriteFile(file, svg, 0o644); err != nil {
+		return nil, fmt.Errorf("writing diagram cache: %w", err)
+	}
+	return svg, nil
+}
+
+// diagramLanguages are the code block languages handed to the diagram
+// renderer.
+var diagramLanguages = map[string]bool{
+	"mermaid": true,
+}
+
+// renderDiagram writes a diagram code block as inline SVG. Errors are kept
+// in errp, since render hooks cannot return them.
+func (c *Converter) renderDiagram(w io.Writer, block *ast.CodeBlock, errp *error) (ast.WalkStatus, bool) {
+	lang := strings.Fields(string(block.Info))
+	if c.opts.Diagrams == nil || len(lang) == 0 || !diagramLanguages[lang[0]] {
+		return ast.GoToNext, false
+	}
+	if *errp != nil {
+		return ast.GoToNext, true
+	}
+
+	svg, err := c.opts.Diagrams.RenderDiagram(lang[0], block.Literal)
+	if err != nil {
+		*errp = fmt.Errorf("%s diagram: %w", lang[0], err)
+		return ast.GoToNext, true
+	}
+	io.WriteString(w, `<div class="diagram diagram-`+lang[0]+`">`)
+	w.Write(svg)
+	io.WriteString(w, "</div>\n")
+	return ast.GoToNext, true
+}
@@ -0,0 +1,111 @@
+package parser_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/integralist/integralist.co.uk/internal/parser"
+)
+
+// stubSVG stands in for mmdc: it wraps its input in an <svg> element after
+// an XML prolog, as real renderers emit.
+var stubSVG = []string{"sh", "-c", `printf '<?xml version="1.0"?>\n<svg>'; cat; printf '</svg>\n'`}
+
+type countingRenderer struct {
+	calls int
+	r     parser.DiagramRenderer
+}
+
+func (c *countingRenderer) RenderDiagram(lang string, src []byte) ([]byte, error) {
+	c.calls++
+	return c.r.RenderDiagram(lang, src)
+}
+
+func TestConvert_MermaidDiagram(t *testing.T) {
+	c, err := parser.NewConverter(parser.Options{
+		Diagrams: &parser.CommandRenderer{Command: stubSVG},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := "```mermaid\ngraph TD; A-->B\n```\n\n```go\nfunc main() {}\n```\n"
+	out, err := c.Convert(parser.Document{Name: "post.md", FirstLine: 1, Body: []byte(md)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(out)
+	if !strings.Contains(got, "<div class=\"diagram diagram-mermaid\"><svg>graph TD; A-->B\n</svg></div>") {
+		t.Errorf("missing inline SVG in %q", got)
+	}
+	if strings.Contains(got, "<?xml") || strings.Contains(got, "language-mermaid") {
+		t.Errorf("diagram not replaced cleanly: %q", got)
+	}
+	if !strings.Contains(got, `class="language-go"`) {
+		t.Errorf("non-diagram code block altered: %q", got)
+	}
+}
+
+// Without a renderer, mermaid blocks stay as code for the client-side script.
+func TestMarkdownToHTML_MermaidWithoutRenderer(t *testing.T) {
+	got := string(parser.MarkdownToHTML([]byte("```mermaid\ngraph TD; A-->B\n```")))
+	if !strings.Contains(got, `class="language-mermaid"`) {
+		t.Errorf("got %q, want mermaid code block", got)
+	}
+}
+
+func TestConvert_DiagramCommandError(t *testing.T) {
+	c, err := parser.NewConverter(parser.Options{
+		Diagrams: &parser.CommandRenderer{Command: []string{"sh", "-c", "echo 'Parse error on line 1' >&2; exit 1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Convert(parser.Document{Name: "post.md", FirstLine: 1, Body: []byte("```mermaid\ngraph TD; A-->\n```")})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "post.md: mermaid diagram") || !strings.Contains(err.Error(), "Parse error on line 1") {
+		t.Errorf("got %q, want file name and command stderr", err)
+	}
+}
+
+func TestCachedRenderer(t *testing.T) {
+	dir := t.TempDir()
+	inner := &countingRenderer{r: &parser.CommandRenderer{Command: stubSVG}}
+	r := &parser.CachedRenderer{Renderer: inner, Dir: dir}
+
+	first, err := r.RenderDiagram("mermaid", []byte("graph TD; A-->B"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := r.RenderDiagram("mermaid", []byte("graph TD; A-->B"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner.calls != 1 {
+		t.Errorf("renderer called %d times, want 1", inner.calls)
+	}
+	if string(first) != string(second) {
+		t.Errorf("cached output %q differs from %q", second, first)
+	}
+
+	if _, err := r.RenderDiagram("mermaid", []byte("graph TD; B-->C")); err != nil {
+		t.Fatal(err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("renderer called %d times after source change, want 2", inner.calls)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.svg"))
+	if len(files) != 2 {
+		t.Errorf("cache holds %d files, want 2", len(files))
+	}
+
+	r.Key = "mmdc --theme dark"
+	if _, err := r.RenderDiagram("mermaid", []byte("graph TD; A-->B")); err != nil {
+		t.Fatal(err)
+	}
+	if inner.calls != 3 {
+		t.Errorf("renderer called %d times after key change, want 3", inner.calls)
+	}
+}
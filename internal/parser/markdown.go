@@ -1,116 +1,236 @@
 package parser
 
 import (
+	"fmt"
+	stdhtml "html"
+	"io"
+	"maps"
 	"regexp"
 	"strings"
 
 	"github.com/gomarkdown/markdown"
+	"github.com/gomarkdown/markdown/ast"
 	"github.com/gomarkdown/markdown/html"
 	mdparser "github.com/gomarkdown/markdown/parser"
 )
 
-var (
-	imgTag         = regexp.MustCompile(`<img\s+src="([^"]+)"([^/]*)/>`)
-	alertTag       = regexp.MustCompile(`(?s)<blockquote>\s*<p>\[!(NOTE|TIP|IMPORTANT|WARNING|CAUTION|ABSTRACT|ATTENTION|BUG|CHECK|CITE|DANGER|DONE|ERROR|EXAMPLE|FAIL|FAILURE|FAQ|HELP|HINT|INFO|MISSING|QUESTION|QUOTE|SUCCESS|SUMMARY|TLDR|TODO)\]\s*\n?(.*?)</p>\s*</blockquote>`)
-	alertParagraph = regexp.MustCompile(`(?s)<p>\[!(NOTE|TIP|IMPORTANT|WARNING|CAUTION|ABSTRACT|ATTENTION|BUG|CHECK|CITE|DANGER|DONE|ERROR|EXAMPLE|FAIL|FAILURE|FAQ|HELP|HINT|INFO|MISSING|QUESTION|QUOTE|SUCCESS|SUMMARY|TLDR|TODO)\]\s*\n?(.*?)</p>`)
-)
+var validAlertType = regexp.MustCompile(`^[A-Za-z]+$`)
 
-var alertIcons = map[string]string{
-	"ABSTRACT":  "\U0001F4CB",
-	"ATTENTION": "⚠️",
-	"BUG":       "\U0001F41B",
-	"CAUTION":   "\U0001F525",
-	"CHECK":     "✅",
-	"CITE":      "\U0001F4AC",
-	"DANGER":    "\U0001F6A8",
-	"DONE":      "✅",
-	"ERROR":     "❌",
-	"EXAMPLE":   "\U0001F4DD",
-	"FAIL":      "❌",
-	"FAILURE":   "❌",
-	"FAQ":       "❓",
-	"HELP":      "❓",
-	"HINT":      "\U0001F4A1",
-	"IMPORTANT": "❗",
-	"INFO":      "ℹ️",
-	"MISSING":   "❌",
-	"NOTE":      "ℹ️",
-	"QUESTION":  "❓",
-	"QUOTE":     "\U0001F4AC",
-	"SUCCESS":   "✅",
-	"SUMMARY":   "\U0001F4CB",
-	"TIP":       "\U0001F4A1",
-	"TLDR":      "\U0001F4CB",
-	"TODO":      "☑️",
-	"WARNING":   "⚠️",
+// Options configures a Converter.
+type Options struct {
+	// Alerts registers extra alert types, or overrides the icon of a
+	// built-in one, keyed by type name (e.g. "RECIPE") with the title icon
+	// as the value.
+	Alerts map[string]string
+	// Anchors adds permalink anchors to headings. The zero value adds none.
+	Anchors AnchorOptions
+	// Diagrams renders ```mermaid code blocks to inline SVG at build time.
+	// Nil leaves them as code blocks for a client-side script to render.
+	Diagrams DiagramRenderer
+	// FootnoteReturnLink is the HTML shown in the link from a footnote back
+	// to its reference. Empty uses the renderer's default.
+	FootnoteReturnLink string
+	// ShortcodeDir holds *.html templates that override the built-in
+	// shortcodes or add new ones.
+	ShortcodeDir string
 }
 
-// MarkdownToHTML converts markdown bytes to HTML bytes.
-func MarkdownToHTML(md []byte) []byte {
-	extensions := mdparser.CommonExtensions | mdparser.AutoHeadingIDs
-	p := mdparser.NewWithExtensions(extensions)
+// AnchorOptions configures heading permalink anchors.
+type AnchorOptions struct {
+	MinLevel int
+	MaxLevel int
+	Class    string
+	Symbol   string
+	Before   bool // place the anchor before the heading text instead of after
+}
 
-	opts := html.RendererOptions{Flags: html.CommonFlags | html.HrefTargetBlank}
-	renderer := html.NewRenderer(opts)
+// Converter converts Markdown to HTML, expanding shortcodes along the way.
+type Converter struct {
+	alerts     map[string]string
+	opts       Options
+	shortcodes map[string]*shortcode
+}
 
-	out := markdown.ToHTML(md, p, renderer)
-	out = wrapImagesInLinks(out)
-	out = transformAlerts(out)
-	return out
+// NewConverter creates a Converter from opts.
+func NewConverter(opts Options) (*Converter, error) {
+	shortcodes, err := loadShortcodes(opts.ShortcodeDir)
+	if err != nil {
+		return nil, err
+	}
+	alerts := maps.Clone(alertIcons)
+	for kind, icon := range opts.Alerts {
+		if !validAlertType.MatchString(kind) {
+			return nil, fmt.Errorf("alert type %q: must contain only letters", kind)
+		}
+		alerts[strings.ToUpper(kind)] = icon
+	}
+
+	return &Converter{alerts: alerts, opts: opts, shortcodes: shortcodes}, nil
 }
 
-func wrapImagesInLinks(html []byte) []byte {
-	return imgTag.ReplaceAllFunc(html, func(match []byte) []byte {
-		groups := imgTag.FindSubmatch(match)
-		src := groups[1]
-		return []byte(`<a href="` + string(src) + `" target="_blank">` + string(match) + `</a>`)
-	})
+var defaultConverter = func() *Converter {
+	c, err := NewConverter(Options{})
+	if err != nil {
+		panic(err)
+	}
+	return c
+}()
+
+// Document is a Markdown source to convert.
+type Document struct {
+	// Name is the source file, used in error messages.
+	Name string
+	// FirstLine is the line of Name on which Body starts.
+	FirstLine int
+	Body      []byte
+	// Math parses $inline$ and $$display$$ TeX. It is opt-in per document
+	// so that prose mentioning prices ($5 or $10) is left alone.
+	Math bool
+
+	// placeholders is the prefix of the shortcode placeholders in Body.
+	placeholders string
+}
+
+// MarkdownToHTML converts markdown bytes to HTML bytes using the built-in
+// shortcodes. A shortcode that fails to expand is left in the output as
+// written; use Converter.Convert to surface the error instead.
+func MarkdownToHTML(md []byte) []byte {
+	doc := Document{FirstLine: 1, Body: md}
+	out, err := defaultConverter.Convert(doc)
+	if err != nil {
+		out, _ = defaultConverter.render(doc)
+	}
+	return out
 }
 
-func transformAlerts(src []byte) []byte {
-	src = splitMergedAlerts(src)
-	return alertTag.ReplaceAllFunc(src, func(match []byte) []byte {
-		groups := alertTag.FindSubmatch(match)
-		alertType := string(groups[1])
-		body := strings.TrimSpace(string(groups[2]))
-		icon := alertIcons[alertType]
-		lower := strings.ToLower(alertType)
-		return []byte(`<div class="alert alert-` + lower + `"><p class="alert-title">` + icon + " " + alertType + `</p><p>` + body + `</p></div>`)
-	})
+// Convert converts doc to HTML. Errors name the document and, where known,
+// the offending line.
+func (c *Converter) Convert(doc Document) ([]byte, error) {
+	body, rendered, prefix, err := c.expandShortcodes(doc.Name, doc.FirstLine, doc.Body)
+	if err != nil {
+		return nil, err
+	}
+	doc.Body = body
+	doc.placeholders = prefix
+
+	out, err := c.render(doc)
+	if err != nil {
+		return nil, err
+	}
+	return restoreShortcodes(out, rendered), nil
 }
 
-func splitMergedAlerts(src []byte) []byte {
-	s := string(src)
-	var result strings.Builder
-	for {
-		start := strings.Index(s, "<blockquote>")
-		if start == -1 {
-			result.WriteString(s)
-			break
+func (c *Converter) render(doc Document) ([]byte, error) {
+	extensions := mdparser.CommonExtensions | mdparser.AutoHeadingIDs | mdparser.Footnotes
+	if !doc.Math {
+		extensions &^= mdparser.MathJax
+	}
+	p := mdparser.NewWithExtensions(extensions)
+	if doc.Math {
+		math := p.RegisterInline('$', nil)
+		p.RegisterInline('$', parseDisplayMath(math))
+	}
+
+	opts := html.RendererOptions{
+		Flags:                      html.CommonFlags | html.HrefTargetBlank | html.FootnoteReturnLinks,
+		FootnoteReturnLinkContents: c.opts.FootnoteReturnLink,
+	}
+	renderer := html.NewRenderer(opts)
+	var hookErr error
+	renderer.Opts.RenderNodeHook = func(w io.Writer, node ast.Node, entering bool) (ast.WalkStatus, bool) {
+		switch n := node.(type) {
+		case *alert:
+			return renderAlert(w, n, entering)
+		case *alertTitle:
+			return renderAlertTitle(w, n, entering)
+		case *ast.Heading:
+			return c.renderHeading(renderer, w, n, entering)
+		case *ast.Image:
+			return renderImage(renderer, w, n, entering)
+		case *ast.CodeBlock:
+			return c.renderDiagram(w, n, &hookErr)
+		case *ast.Math, *ast.MathBlock, *displayMath:
+			return renderMath(w, n, entering)
+		}
+		return ast.GoToNext, false
+	}
+
+	root := p.Parse(doc.Body)
+	if doc.placeholders != "" {
+		stripPlaceholderIDs(root, doc.placeholders)
+	}
+	if doc.Math {
+		if line, err := checkMath(root, doc.Body, doc.FirstLine); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", doc.Name, line, err)
 		}
-		end := strings.Index(s[start:], "</blockquote>")
-		if end == -1 {
-			result.WriteString(s)
-			break
+	}
+	transformAlerts(root, c.alerts)
+	out := markdown.Render(root, renderer)
+	if hookErr != nil {
+		return nil, fmt.Errorf("%s: %w", doc.Name, hookErr)
+	}
+	return out, nil
+}
+
+// renderHeading adds a permalink anchor to headings within the configured
+// levels. IDs come from the heading text or an explicit {#id}.
+func (c *Converter) renderHeading(r *html.Renderer, w io.Writer, h *ast.Heading, entering bool) (ast.WalkStatus, bool) {
+	a := c.opts.Anchors
+	if h.Level < a.MinLevel || h.Level > a.MaxLevel {
+		return ast.GoToNext, false
+	}
+
+	// HeadingEnter settles the final, de-duplicated ID, so the anchor can
+	// only be written once it has run.
+	if entering {
+		r.HeadingEnter(w, h)
+		if a.Before {
+			writeAnchor(w, h.HeadingID, a)
 		}
-		end += start + len("</blockquote>")
-
-		result.WriteString(s[:start])
-		block := s[start:end]
-		inner := block[len("<blockquote>") : len(block)-len("</blockquote>")]
-
-		paragraphs := alertParagraph.FindAllString(inner, -1)
-		if len(paragraphs) >= 2 {
-			for _, p := range paragraphs {
-				result.WriteString("<blockquote>\n")
-				result.WriteString(p)
-				result.WriteString("\n</blockquote>\n")
-			}
-		} else {
-			result.WriteString(block)
+	} else {
+		if !a.Before {
+			writeAnchor(w, h.HeadingID, a)
 		}
+		r.HeadingExit(w, h)
+	}
+	return ast.GoToNext, true
+}
+
+func writeAnchor(w io.Writer, id string, a AnchorOptions) {
+	if id == "" {
+		return
+	}
+	id = stdhtml.EscapeString(id)
+	link := `<a class="` + stdhtml.EscapeString(a.Class) + `" href="#` + id + `" aria-label="Permalink to this section">` + a.Symbol + `</a>`
+	if a.Before {
+		io.WriteString(w, link+" ")
+		return
+	}
+	io.WriteString(w, " "+link)
+}
+
+// renderImage wraps images in a link to the full-size file, unless the
+// author has already put the image inside a link of their own.
+func renderImage(r *html.Renderer, w io.Writer, img *ast.Image, entering bool) (ast.WalkStatus, bool) {
+	if insideLink(img) {
+		return ast.GoToNext, false
+	}
+	if entering {
+		io.WriteString(w, `<a href="`)
+		html.EscLink(w, img.Destination)
+		io.WriteString(w, `" target="_blank">`)
+		return ast.GoToNext, false
+	}
+	r.Image(w, img, false)
+	io.WriteString(w, `</a>`)
+	return ast.GoToNext, true
+}
 
-		s = s[end:]
+func insideLink(node ast.Node) bool {
+	for p := node.GetParent(); p != nil; p = p.GetParent() {
+		if _, ok := p.(*ast.Link); ok {
+			return true
+		}
 	}
-	return []byte(result.String())
+	return false
 }
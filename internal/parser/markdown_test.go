@@ -1,6 +1,7 @@
 package parser_test
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -140,3 +141,304 @@ func TestMarkdownToHTML_AlertBlockquotes(t *testing.T) {
 		})
 	}
 }
+
+// Verifies that headings within the configured levels get permalink anchors.
+func TestConvert_HeadingAnchors(t *testing.T) {
+	c, err := parser.NewConverter(parser.Options{
+		Anchors: parser.AnchorOptions{MinLevel: 2, MaxLevel: 4, Class: "heading-anchor", Symbol: "#"},
+	})
+	if err != nil {
+		t.Fatalf("NewConverter error: %v", err)
+	}
+
+	out, err := c.Convert(parser.Document{Name: "post.md", FirstLine: 1, Body: []byte("# Top\n\n## Section One\n\n##### Deep\n")})
+	if err != nil {
+		t.Fatalf("Convert error: %v", err)
+	}
+	got := string(out)
+	if !strings.Contains(got, `<h2 id="section-one">Section One <a class="heading-anchor" href="#section-one"`) {
+		t.Errorf("h2 missing anchor after text:\n%s", got)
+	}
+	if strings.Count(got, "heading-anchor") != 1 {
+		t.Errorf("only h2-h4 should get anchors:\n%s", got)
+	}
+}
+
+// Verifies that anchors can be placed before the heading text.
+func TestConvert_HeadingAnchorsBefore(t *testing.T) {
+	c, err := parser.NewConverter(parser.Options{
+		Anchors: parser.AnchorOptions{MinLevel: 2, MaxLevel: 2, Class: "anchor", Symbol: "§", Before: true},
+	})
+	if err != nil {
+		t.Fatalf("NewConverter error: %v", err)
+	}
+
+	out, err := c.Convert(parser.Document{Name: "post.md", FirstLine: 1, Body: []byte("## Title {#custom-id}\n")})
+	if err != nil {
+		t.Fatalf("Convert error: %v", err)
+	}
+	got := string(out)
+	if !strings.Contains(got, `<h2 id="custom-id"><a class="anchor" href="#custom-id" aria-label="Permalink to this section">§</a> Title</h2>`) {
+		t.Errorf("got %q, want anchor before text using explicit ID", got)
+	}
+}
+
+// Verifies that duplicate heading text gets unique IDs and matching anchors.
+func TestConvert_HeadingAnchorsUniqueIDs(t *testing.T) {
+	c, err := parser.NewConverter(parser.Options{
+		Anchors: parser.AnchorOptions{MinLevel: 2, MaxLevel: 4, Class: "a", Symbol: "#"},
+	})
+	if err != nil {
+		t.Fatalf("NewConverter error: %v", err)
+	}
+
+	out, err := c.Convert(parser.Document{Name: "post.md", FirstLine: 1, Body: []byte("## Setup\n\n## Setup\n")})
+	if err != nil {
+		t.Fatalf("Convert error: %v", err)
+	}
+	got := string(out)
+	if !strings.Contains(got, `id="setup-1"`) || !strings.Contains(got, `href="#setup-1"`) {
+		t.Errorf("second heading anchor should use de-duplicated ID:\n%s", got)
+	}
+}
+
+func TestMarkdownToHTML_Footnotes(t *testing.T) {
+	got := string(parser.MarkdownToHTML([]byte("Claim.[^1]\n\n[^1]: Source.\n")))
+	if !strings.Contains(got, `class="footnotes"`) {
+		t.Errorf("missing footnotes list:\n%s", got)
+	}
+	if !strings.Contains(got, `href="#fn:1"`) {
+		t.Errorf("missing footnote reference link:\n%s", got)
+	}
+	if !strings.Contains(got, `href="#fnref:1"`) {
+		t.Errorf("missing footnote backlink:\n%s", got)
+	}
+}
+
+func TestMarkdownToHTML_DefinitionList(t *testing.T) {
+	got := string(parser.MarkdownToHTML([]byte("Goroutine\n: A lightweight thread.\n")))
+	if !strings.Contains(got, "<dt>Goroutine</dt>") || !strings.Contains(got, "<dd>A lightweight thread.</dd>") {
+		t.Errorf("got %q, want definition list", got)
+	}
+}
+
+// Verifies that alert bodies keep every block, not just the first paragraph.
+func TestMarkdownToHTML_AlertMultiBlockBody(t *testing.T) {
+	input := "> [!NOTE]\n> First paragraph.\n>\n> - one\n> - two\n>\n> ```go\n> fmt.Println(\"hi\")\n> ```\n>\n> Last paragraph.\n"
+	got := string(parser.MarkdownToHTML([]byte(input)))
+
+	if strings.Count(got, `class="alert `) != 1 {
+		t.Fatalf("expected 1 alert div, got HTML:\n%s", got)
+	}
+	for _, want := range []string{"<p>First paragraph.</p>", "<li>one</li>", "<pre><code", "<p>Last paragraph.</p>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("alert body missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "Last paragraph.") > strings.Index(got, "</div>") {
+		t.Errorf("alert closed before the end of its body:\n%s", got)
+	}
+}
+
+// Verifies that text after the marker becomes the alert title.
+func TestMarkdownToHTML_AlertCustomTitle(t *testing.T) {
+	got := string(parser.MarkdownToHTML([]byte("> [!WARNING] Salt & pepper\n> Body text.\n")))
+	if !strings.Contains(got, `<p class="alert-title">⚠️ Salt &amp; pepper</p>`) {
+		t.Errorf("missing custom title:\n%s", got)
+	}
+	if !strings.Contains(got, "<p>Body text.</p>") {
+		t.Errorf("missing body:\n%s", got)
+	}
+}
+
+// Verifies that inline markup in a custom title renders in the title and
+// stays out of the body.
+func TestMarkdownToHTML_AlertTitleMarkup(t *testing.T) {
+	got := string(parser.MarkdownToHTML([]byte("> [!NOTE] Custom *title* with `code` and [a link](/x)\n> Body *text*.\n")))
+	want := `<p class="alert-title">ℹ️ Custom <em>title</em> with <code>code</code> and <a href="/x">a link</a></p>`
+	if !strings.Contains(got, want) {
+		t.Errorf("got %q, want title %q", got, want)
+	}
+	if !strings.Contains(got, "<p>Body <em>text</em>.</p>") {
+		t.Errorf("got %q, want the body without the title", got)
+	}
+
+	got = string(parser.MarkdownToHTML([]byte("> [!TIP]- Read *this*\n> Hidden.\n")))
+	if !strings.Contains(got, `<summary class="alert-title">💡 Read <em>this</em></summary><p>Hidden.</p>`) {
+		t.Errorf("got %q, want a foldable alert with a marked-up summary", got)
+	}
+}
+
+// Verifies that a blockquote nested in an alert stays a blockquote.
+func TestMarkdownToHTML_AlertNestedBlockquote(t *testing.T) {
+	got := string(parser.MarkdownToHTML([]byte("> [!QUOTE]\n> Someone said:\n>\n> > Nested quote.\n")))
+	if !strings.Contains(got, "alert-quote") {
+		t.Errorf("missing alert:\n%s", got)
+	}
+	if !strings.Contains(got, "<blockquote>") || !strings.Contains(got, "Nested quote.") {
+		t.Errorf("nested blockquote lost:\n%s", got)
+	}
+}
+
+// Verifies that plain blockquotes and unknown markers are left alone.
+func TestMarkdownToHTML_PlainBlockquote(t *testing.T) {
+	for _, input := range []string{"> Just a quote.", "> [!UNKNOWN]\n> Not an alert."} {
+		got := string(parser.MarkdownToHTML([]byte(input)))
+		if !strings.Contains(got, "<blockquote>") || strings.Contains(got, `class="alert`) {
+			t.Errorf("input %q should stay a blockquote:\n%s", input, got)
+		}
+	}
+}
+
+// Verifies that images with a title containing "/" are still wrapped in a link.
+func TestMarkdownToHTML_ImageWithSlashInTitle(t *testing.T) {
+	got := string(parser.MarkdownToHTML([]byte(`![alt](/assets/img/a.png "before/after")`)))
+	if !strings.Contains(got, `<a href="/assets/img/a.png" target="_blank"><img src="/assets/img/a.png" alt="alt" title="before/after" /></a>`) {
+		t.Errorf("got %q, want image wrapped in link", got)
+	}
+}
+
+// Verifies that an image the author already linked is not wrapped a second time.
+func TestMarkdownToHTML_LinkedImageNotDoubleWrapped(t *testing.T) {
+	got := string(parser.MarkdownToHTML([]byte(`[![alt](/assets/img/a.png)](https://example.com)`)))
+	if strings.Count(got, "<a ") != 1 {
+		t.Errorf("got %q, want a single link", got)
+	}
+	if !strings.Contains(got, `href="https://example.com"`) {
+		t.Errorf("got %q, want the author's link kept", got)
+	}
+}
+
+func TestMarkdownToHTML_FoldableAlerts(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"collapsed", "> [!TIP]-\n> Hidden until opened.", `<details class="alert alert-tip"><summary class="alert-title">💡 TIP</summary>`},
+		{"expanded", "> [!TIP]+ Open by default\n> Shown.", `<details class="alert alert-tip" open><summary class="alert-title">💡 Open by default</summary>`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := string(parser.MarkdownToHTML([]byte(tc.input)))
+			if !strings.Contains(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			if !strings.Contains(got, "</details>") || strings.Contains(got, "<div") {
+				t.Errorf("foldable alert should render as details only: %q", got)
+			}
+		})
+	}
+}
+
+// Verifies that alert types registered through options are recognised.
+func TestConvert_CustomAlertTypes(t *testing.T) {
+	c, err := parser.NewConverter(parser.Options{
+		Alerts: map[string]string{"recipe": "🍳", "NOTE": "📝"},
+	})
+	if err != nil {
+		t.Fatalf("NewConverter error: %v", err)
+	}
+
+	out, err := c.Convert(parser.Document{Name: "post.md", FirstLine: 1, Body: []byte("> [!RECIPE]\n> Crack two eggs.\n\n> [!NOTE]\n> Noted.")})
+	if err != nil {
+		t.Fatalf("Convert error: %v", err)
+	}
+	got := string(out)
+	if !strings.Contains(got, `<div class="alert alert-recipe"><p class="alert-title">🍳 RECIPE</p>`) {
+		t.Errorf("custom alert type not rendered:\n%s", got)
+	}
+	if !strings.Contains(got, `📝 NOTE`) {
+		t.Errorf("built-in icon not overridden:\n%s", got)
+	}
+}
+
+func TestNewConverter_InvalidAlertType(t *testing.T) {
+	if _, err := parser.NewConverter(parser.Options{Alerts: map[string]string{"NOT OK": "x"}}); err == nil {
+		t.Error("expected error for alert type with spaces, got nil")
+	}
+}
+
+// Verifies that inline and display math are written once each, in the
+// delimiters the math script looks for.
+func TestConvert_Math(t *testing.T) {
+	c, err := parser.NewConverter(parser.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]struct {
+		input string
+		want  string
+	}{
+		"inline": {
+			"Inserts are $O(\\log n)$ and $a<b$.",
+			`<p>Inserts are <span class="math math-inline">\(O(\log n)\)</span> and <span class="math math-inline">\(a&lt;b\)</span>.</p>` + "\n",
+		},
+		"display block": {
+			"$$\nT(n) = 2T\\left(\\frac{n}{2}\\right)\n$$\n",
+			`<div class="math math-display">\[T(n) = 2T\left(\frac{n}{2}\right)\]</div>` + "\n",
+		},
+		"display on one line": {
+			"$$x^2$$",
+			`<div class="math math-display">\[x^2\]</div>` + "\n",
+		},
+		"display in a paragraph": {
+			"Energy $$y$$ and $$z$$ here.",
+			`<p>Energy <span class="math math-display">\[y\]</span> and <span class="math math-display">\[z\]</span> here.</p>` + "\n",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			out, err := c.Convert(parser.Document{Name: "post.md", FirstLine: 1, Body: []byte(tc.input), Math: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tc.want {
+				t.Errorf("got %q, want %q", out, tc.want)
+			}
+		})
+	}
+}
+
+// Dollar signs are plain text unless the document opts in to math.
+func TestConvert_MathOptIn(t *testing.T) {
+	got := string(parser.MarkdownToHTML([]byte("It costs $5 or $10.")))
+	if !strings.Contains(got, "<p>It costs $5 or $10.</p>") {
+		t.Errorf("got %q, want dollars left as text", got)
+	}
+}
+
+// Verifies that malformed math is reported with the file and line it is on.
+func TestConvert_MathErrors(t *testing.T) {
+	c, err := parser.NewConverter(parser.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]struct {
+		input string
+		line  int
+		want  string
+	}{
+		"unclosed brace":  {"$\\frac{1}{2$", 4, "unclosed {"},
+		"stray brace":     {"$a}$", 4, "unexpected }"},
+		"environment":     {"$$\n\\begin{matrix} a \\end{pmatrix}\n$$", 5, `\end{pmatrix} without matching \begin`},
+		"unclosed env":    {"$$\n\\begin{aligned} a\n$$", 5, `\begin{aligned} is never closed`},
+		"left right":      {"$\\left( x$", 4, `unbalanced \left and \right`},
+		"unterminated $$": {"$$\nx^2\n", 4, "unterminated $$"},
+		"later line":      {"Intro.\n\nSee $x$ and $y$.\n\n$a}$", 8, "unexpected }"},
+		"display in text": {"See $$a}$$ here.", 4, "unexpected }"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := c.Convert(parser.Document{Name: "post.md", FirstLine: 4, Body: []byte(tc.input), Math: true})
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			prefix := fmt.Sprintf("post.md:%d: ", tc.line)
+			if !strings.HasPrefix(err.Error(), prefix) || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("got %q, want %q and %q", err, prefix, tc.want)
+			}
+		})
+	}
+}
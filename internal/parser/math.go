@@ -0,0 +1,160 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	stdhtml "html"
+	"io"
+	"regexp"
+
+	"github.com/gomarkdown/markdown/ast"
+	mdparser "github.com/gomarkdown/markdown/parser"
+)
+
+var (
+	texEnvironment = regexp.MustCompile(`\\(begin|end)\{([^}]*)\}`)
+	texLeftRight   = regexp.MustCompile(`\\(left|right)\b`)
+)
+
+// checkMath reports the first malformed TeX expression under node, and the
+// line of src, counting from firstLine, that it is on. It catches the
+// mistakes that would otherwise surface as a red error box in the reader's
+// browser: unbalanced braces, mismatched environments and \left/\right
+// pairs, and $$ delimiters the parser could not pair up.
+func checkMath(node ast.Node, src []byte, firstLine int) (int, error) {
+	var err error
+	// The AST has no positions, so each expression is found in src by
+	// searching on from the previous one.
+	offset := 0
+	locate := func(literal []byte) {
+		if i := bytes.Index(src[offset:], literal); i >= 0 {
+			offset += i
+		}
+	}
+	ast.WalkFunc(node, func(n ast.Node, entering bool) ast.WalkStatus {
+		if !entering {
+			return ast.GoToNext
+		}
+		switch n := n.(type) {
+		case *ast.Math:
+			locate(n.Literal)
+			err = checkTeX(n.Literal)
+		case *displayMath:
+			locate(n.Literal)
+			err = checkTeX(n.Literal)
+		case *ast.MathBlock:
+			locate(bytes.TrimSpace(n.Literal))
+			err = checkTeX(n.Literal)
+		case *ast.Text:
+			if bytes.Contains(n.Literal, []byte("$$")) {
+				locate([]byte("$$"))
+				err = fmt.Errorf("unterminated $$ display math near %q", snippet(n.Literal))
+			}
+		}
+		if err != nil {
+			return ast.Terminate
+		}
+		return ast.GoToNext
+	})
+	return firstLine + bytes.Count(src[:offset], []byte("\n")), err
+}
+
+func checkTeX(tex []byte) error {
+	if len(bytes.TrimSpace(tex)) == 0 {
+		return fmt.Errorf("empty math expression")
+	}
+
+	depth := 0
+	for i := 0; i < len(tex); i++ {
+		switch tex[i] {
+		case '\\':
+			i++ // skip the escaped character, e.g. \{ or \}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("math %q: unexpected }", snippet(tex))
+			}
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("math %q: unclosed {", snippet(tex))
+	}
+
+	var envs []string
+	for _, m := range texEnvironment.FindAllSubmatch(tex, -1) {
+		name := string(m[2])
+		if string(m[1]) == "begin" {
+			envs = append(envs, name)
+			continue
+		}
+		if len(envs) == 0 || envs[len(envs)-1] != name {
+			return fmt.Errorf("math %q: \\end{%s} without matching \\begin", snippet(tex), name)
+		}
+		envs = envs[:len(envs)-1]
+	}
+	if len(envs) > 0 {
+		return fmt.Errorf("math %q: \\begin{%s} is never closed", snippet(tex), envs[len(envs)-1])
+	}
+
+	balance := 0
+	for _, m := range texLeftRight.FindAllSubmatch(tex, -1) {
+		if string(m[1]) == "left" {
+			balance++
+		} else {
+			balance--
+		}
+	}
+	if balance != 0 {
+		return fmt.Errorf("math %q: unbalanced \\left and \\right", snippet(tex))
+	}
+	return nil
+}
+
+func snippet(b []byte) string {
+	b = bytes.TrimSpace(b)
+	if len(b) > 40 {
+		return string(b[:40]) + "…"
+	}
+	return string(b)
+}
+
+// displayMath is $$…$$ written inside a paragraph.
+type displayMath struct {
+	ast.Leaf
+}
+
+// parseDisplayMath wraps next, the parser's inline math parser, to read
+// $$…$$ within a paragraph as display math. next alone reads it as inline
+// math between two stray dollar signs.
+func parseDisplayMath(next mdparser.InlineParser) mdparser.InlineParser {
+	return func(p *mdparser.Parser, data []byte, offset int) (int, ast.Node) {
+		rest := data[offset:]
+		if bytes.HasPrefix(rest, []byte("$$")) && !bytes.HasPrefix(rest, []byte("$$$")) {
+			if end := bytes.Index(rest[2:], []byte("$$")); end > 0 {
+				d := &displayMath{}
+				d.Literal = rest[2 : 2+end]
+				return end + 4, d
+			}
+		}
+		return next(p, data, offset)
+	}
+}
+
+// renderMath writes math in the delimiters KaTeX's auto-render extension
+// looks for, leaving typesetting to the opt-in math script.
+func renderMath(w io.Writer, node ast.Node, entering bool) (ast.WalkStatus, bool) {
+	if !entering {
+		return ast.SkipChildren, true
+	}
+	switch n := node.(type) {
+	case *ast.Math:
+		io.WriteString(w, `<span class="math math-inline">\(`+stdhtml.EscapeString(string(n.Literal))+`\)</span>`)
+	case *displayMath:
+		io.WriteString(w, `<span class="math math-display">\[`+stdhtml.EscapeString(string(bytes.TrimSpace(n.Literal)))+`\]</span>`)
+	case *ast.MathBlock:
+		io.WriteString(w, "<div class=\"math math-display\">\\["+stdhtml.EscapeString(string(bytes.TrimSpace(n.Literal)))+"\\]</div>\n")
+	}
+	return ast.SkipChildren, true
+}
@@ -0,0 +1,268 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gomarkdown/markdown/ast"
+)
+
+var (
+	shortcodeTag = regexp.MustCompile(`\{\{<\s*([A-Za-z][\w-]*)(.*?)>\}\}`)
+	shortcodeArg = regexp.MustCompile(`^\s*([A-Za-z][\w-]*)="([^"]*)"`)
+	codeFence    = regexp.MustCompile("^\\s*(`{3,}|~{3,})")
+	listItem     = regexp.MustCompile(`^ {0,3}([-*+]|\d+[.)])[ \t]`)
+)
+
+// shortcode is a named embed authors can use in Markdown as
+// {{< name key="value" >}}.
+type shortcode struct {
+	params   []string // accepted parameters; nil accepts any
+	required []string
+	tmpl     *template.Template
+}
+
+type builtinShortcode struct {
+	params   []string
+	required []string
+	src      string
+}
+
+var builtinShortcodes = map[string]builtinShortcode{
+	"figure": {
+		params:   []string{"alt", "caption", "src"},
+		required: []string{"src"},
+		src:      `<figure><a href="{{.src}}" target="_blank"><img src="{{.src}}" alt="{{.alt}}"></a>{{with .caption}}<figcaption>{{.}}</figcaption>{{end}}</figure>`,
+	},
+	"gist": {
+		params:   []string{"file", "id", "user"},
+		required: []string{"id", "user"},
+		src:      `<script src="https://gist.github.com/{{.user}}/{{.id}}.js{{with .file}}?file={{.}}{{end}}"></script>`,
+	},
+	"youtube": {
+		params:   []string{"id", "title"},
+		required: []string{"id"},
+		src:      `<div class="video"><iframe src="https://www.youtube-nocookie.com/embed/{{.id}}" title="{{or .title "YouTube video"}}" loading="lazy" allowfullscreen></iframe></div>`,
+	},
+}
+
+// loadShortcodes returns the built-in shortcodes, overridden or extended by
+// any *.html templates in dir. A missing dir is not an error.
+func loadShortcodes(dir string) (map[string]*shortcode, error) {
+	codes := make(map[string]*shortcode, len(builtinShortcodes))
+	for name, b := range builtinShortcodes {
+		tmpl, err := template.New(name).Option("missingkey=zero").Parse(b.src)
+		if err != nil {
+			return nil, fmt.Errorf("parsing built-in shortcode %s: %w", name, err)
+		}
+		codes[name] = &shortcode{params: b.params, required: b.required, tmpl: tmpl}
+	}
+
+	if dir == "" {
+		return codes, nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".html")
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := template.New(name).Option("missingkey=zero").Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("parsing shortcode %s: %w", file, err)
+		}
+		if sc, ok := codes[name]; ok {
+			sc.tmpl = tmpl
+			continue
+		}
+		codes[name] = &shortcode{tmpl: tmpl}
+	}
+	return codes, nil
+}
+
+// expandShortcodes replaces each shortcode in md with a placeholder and
+// returns the rendered HTML for each placeholder, and the prefix every
+// placeholder starts with. Shortcodes inside fenced or indented code blocks
+// and inline code spans are left alone so they can be documented.
+func (c *Converter) expandShortcodes(name string, firstLine int, md []byte) ([]byte, map[string][]byte, string, error) {
+	if !bytes.Contains(md, []byte("{{<")) {
+		return md, nil, "", nil
+	}
+
+	prefix := placeholderPrefix(md)
+	rendered := make(map[string][]byte)
+	lines := strings.SplitAfter(string(md), "\n")
+	var (
+		fence     string // the opening fence of the code block being read
+		indented  bool   // reading an indented code block
+		inList    bool   // indented lines continue a list item, not code
+		prevBlank = true
+		out       strings.Builder
+	)
+	for i, line := range lines {
+		blank := strings.TrimSpace(line) == ""
+		if fence != "" {
+			if closesFence(line, fence) {
+				fence = ""
+			}
+			out.WriteString(line)
+			prevBlank = blank
+			continue
+		}
+
+		isIndented := strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")
+		if (indented && (isIndented || blank)) || (isIndented && prevBlank && !inList) {
+			indented = true
+			out.WriteString(line)
+			prevBlank = blank
+			continue
+		}
+		indented = false
+
+		switch {
+		case listItem.MatchString(line):
+			inList = true
+		case !blank && !isIndented && prevBlank:
+			inList = false
+		}
+		prevBlank = blank
+
+		if m := codeFence.FindStringSubmatch(line); m != nil {
+			fence = m[1]
+			out.WriteString(line)
+			continue
+		}
+
+		var expandErr error
+		line = replaceOutsideCode(line, func(match string) string {
+			if expandErr != nil {
+				return match
+			}
+			html, err := c.renderShortcode(match)
+			if err != nil {
+				expandErr = fmt.Errorf("%s:%d: %w", name, firstLine+i, err)
+				return match
+			}
+			key := placeholder(prefix, len(rendered))
+			rendered[key] = html
+			return key
+		})
+		if expandErr != nil {
+			return nil, nil, "", expandErr
+		}
+		out.WriteString(line)
+	}
+	return []byte(out.String()), rendered, prefix, nil
+}
+
+// closesFence reports whether line closes a code block opened with fence: it
+// must be made of the same character, at least as many times.
+func closesFence(line, fence string) bool {
+	s := strings.TrimSpace(line)
+	return len(s) >= len(fence) && strings.Trim(s, fence[:1]) == ""
+}
+
+// replaceOutsideCode applies fn to every shortcode in line that is not inside
+// an inline code span.
+func replaceOutsideCode(line string, fn func(string) string) string {
+	var out strings.Builder
+	last := 0
+	for _, loc := range shortcodeTag.FindAllStringIndex(line, -1) {
+		if strings.Count(line[:loc[0]], "`")%2 == 1 {
+			continue
+		}
+		out.WriteString(line[last:loc[0]])
+		out.WriteString(fn(line[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	out.WriteString(line[last:])
+	return out.String()
+}
+
+func (c *Converter) renderShortcode(tag string) ([]byte, error) {
+	m := shortcodeTag.FindStringSubmatch(tag)
+	name, rawArgs := m[1], m[2]
+
+	sc, ok := c.shortcodes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown shortcode %q", name)
+	}
+
+	args := make(map[string]string)
+	rest := rawArgs
+	for strings.TrimSpace(rest) != "" {
+		am := shortcodeArg.FindStringSubmatch(rest)
+		if am == nil {
+			return nil, fmt.Errorf("shortcode %q: malformed arguments %q (want key=\"value\")", name, strings.TrimSpace(rest))
+		}
+		key := am[1]
+		if sc.params != nil && !slices.Contains(sc.params, key) {
+			return nil, fmt.Errorf("shortcode %q: unknown argument %q (accepts %s)", name, key, strings.Join(sc.params, ", "))
+		}
+		args[key] = am[2]
+		rest = rest[len(am[0]):]
+	}
+	for _, key := range sc.required {
+		if args[key] == "" {
+			return nil, fmt.Errorf("shortcode %q: missing required argument %q", name, key)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := sc.tmpl.Execute(&buf, args); err != nil {
+		return nil, fmt.Errorf("shortcode %q: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// placeholderPrefix returns a lower-case prefix for shortcode placeholders
+// that md does not contain in any case. Placeholders then can't be mistaken
+// for the author's text, even once lower-cased in a heading ID.
+func placeholderPrefix(md []byte) string {
+	lower := bytes.ToLower(md)
+	prefix := "shortcode"
+	for bytes.Contains(lower, []byte(prefix)) {
+		prefix += "x"
+	}
+	return prefix
+}
+
+// placeholder returns a token that survives Markdown rendering untouched.
+func placeholder(prefix string, i int) string {
+	return prefix + strconv.Itoa(i) + "end"
+}
+
+// stripPlaceholderIDs removes placeholders starting with prefix from the
+// automatic IDs of headings under node, so that a heading holding a shortcode
+// is identified by its text alone.
+func stripPlaceholderIDs(node ast.Node, prefix string) {
+	pattern := regexp.MustCompile(`-?` + prefix + `\d+end`)
+	ast.WalkFunc(node, func(n ast.Node, entering bool) ast.WalkStatus {
+		if h, ok := n.(*ast.Heading); ok && entering {
+			h.HeadingID = strings.Trim(pattern.ReplaceAllString(h.HeadingID, ""), "-")
+		}
+		return ast.GoToNext
+	})
+}
+
+// restoreShortcodes swaps placeholders in rendered HTML for shortcode output.
+// A shortcode on a line of its own is rendered as a block rather than being
+// wrapped in a paragraph.
+func restoreShortcodes(html []byte, rendered map[string][]byte) []byte {
+	for key, out := range rendered {
+		html = bytes.ReplaceAll(html, []byte("<p>"+key+"</p>"), out)
+		html = bytes.ReplaceAll(html, []byte(key), out)
+	}
+	return html
+}
@@ -0,0 +1,203 @@
+package parser_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/integralist/integralist.co.uk/internal/parser"
+)
+
+func TestConvert_BuiltinShortcodes(t *testing.T) {
+	c, err := parser.NewConverter(parser.Options{})
+	if err != nil {
+		t.Fatalf("NewConverter error: %v", err)
+	}
+
+	testCases := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			"figure",
+			`{{< figure src="/assets/img/a.png" caption="A caption" >}}`,
+			[]string{`<figure>`, `src="/assets/img/a.png"`, `<figcaption>A caption</figcaption>`},
+		},
+		{
+			"youtube",
+			`{{< youtube id="dQw4w9WgXcQ" >}}`,
+			[]string{`<iframe src="https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ"`},
+		},
+		{
+			"gist",
+			`{{< gist user="integralist" id="abc123" >}}`,
+			[]string{`src="https://gist.github.com/integralist/abc123.js"`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := c.Convert(parser.Document{Name: "post.md", FirstLine: 1, Body: []byte(tc.input)})
+			if err != nil {
+				t.Fatalf("Convert error: %v", err)
+			}
+			got := string(out)
+			for _, want := range tc.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("got %q, want %q", got, want)
+				}
+			}
+			if strings.Contains(got, "<p><figure") || strings.Contains(got, "<p><div") {
+				t.Errorf("block shortcode wrapped in paragraph: %q", got)
+			}
+		})
+	}
+}
+
+// Verifies that shortcode errors name the file and line they occur on.
+func TestConvert_ShortcodeErrors(t *testing.T) {
+	c, err := parser.NewConverter(parser.Options{})
+	if err != nil {
+		t.Fatalf("NewConverter error: %v", err)
+	}
+
+	testCases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"unknown", "Intro.\n\n{{< tweet id=\"1\" >}}", `post.md:7: unknown shortcode "tweet"`},
+		{"missing arg", "{{< figure caption=\"x\" >}}", `post.md:5: shortcode "figure": missing required argument "src"`},
+		{"unknown arg", "{{< youtube id=\"x\" width=\"1\" >}}", `post.md:5: shortcode "youtube": unknown argument "width"`},
+		{"malformed", "{{< figure src=/a.png >}}", `post.md:5: shortcode "figure": malformed arguments`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := c.Convert(parser.Document{Name: "post.md", FirstLine: 5, Body: []byte(tc.input)})
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error = %q, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+}
+
+// Verifies that shortcodes inside code are shown rather than expanded.
+func TestConvert_ShortcodeInCodeUntouched(t *testing.T) {
+	c, err := parser.NewConverter(parser.Options{})
+	if err != nil {
+		t.Fatalf("NewConverter error: %v", err)
+	}
+
+	input := "Use `{{< nope >}}` inline.\n\n```\n{{< nope >}}\n```\n"
+	out, err := c.Convert(parser.Document{Name: "post.md", FirstLine: 1, Body: []byte(input)})
+	if err != nil {
+		t.Fatalf("Convert error: %v", err)
+	}
+	if strings.Count(string(out), "nope") != 2 {
+		t.Errorf("got %q, want both shortcodes left as code", out)
+	}
+}
+
+// Verifies that shortcodes in indented code blocks, and in fenced blocks
+// holding a fence of the other kind, are shown rather than expanded.
+func TestConvert_ShortcodeInCodeBlocks(t *testing.T) {
+	c, err := parser.NewConverter(parser.Options{})
+	if err != nil {
+		t.Fatalf("NewConverter error: %v", err)
+	}
+
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{"indented", "Example:\n\n    {{< nope >}}\n"},
+		{"indented after blank line", "Example:\n\n    line one\n\n    {{< nope >}}\n"},
+		{"backticks in tildes", "~~~\n```\n{{< nope >}}\n```\n~~~\n"},
+		{"short closing fence", "````\n```\n{{< nope >}}\n````\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := c.Convert(parser.Document{Name: "post.md", FirstLine: 1, Body: []byte(tc.input)})
+			if err != nil {
+				t.Fatalf("Convert error: %v", err)
+			}
+			if !strings.Contains(string(out), "{{&lt; nope &gt;}}") {
+				t.Errorf("got %q, want the shortcode left as code", out)
+			}
+		})
+	}
+}
+
+// Verifies that a shortcode indented under a list item is still expanded.
+func TestConvert_ShortcodeInListItem(t *testing.T) {
+	c, err := parser.NewConverter(parser.Options{})
+	if err != nil {
+		t.Fatalf("NewConverter error: %v", err)
+	}
+
+	out, err := c.Convert(parser.Document{Name: "post.md", FirstLine: 1, Body: []byte("- Item\n\n    {{< youtube id=\"x\" >}}\n")})
+	if err != nil {
+		t.Fatalf("Convert error: %v", err)
+	}
+	if !strings.Contains(string(out), "youtube-nocookie.com/embed/x") {
+		t.Errorf("got %q, want the shortcode expanded", out)
+	}
+}
+
+// Verifies that placeholders stay out of heading IDs and never replace text
+// the author wrote.
+func TestConvert_ShortcodePlaceholders(t *testing.T) {
+	c, err := parser.NewConverter(parser.Options{})
+	if err != nil {
+		t.Fatalf("NewConverter error: %v", err)
+	}
+
+	out, err := c.Convert(parser.Document{Name: "post.md", FirstLine: 1, Body: []byte("## Watch {{< youtube id=\"x\" >}}\n\nSee shortcode0end and SHORTCODE0PLACEHOLDER.\n")})
+	if err != nil {
+		t.Fatalf("Convert error: %v", err)
+	}
+	got := string(out)
+	if !strings.Contains(got, `<h2 id="watch">`) {
+		t.Errorf("got %q, want heading ID without the placeholder", got)
+	}
+	if !strings.Contains(got, "<p>See shortcode0end and SHORTCODE0PLACEHOLDER.</p>") {
+		t.Errorf("got %q, want the author's text left alone", got)
+	}
+	if strings.Count(got, "youtube-nocookie.com") != 1 {
+		t.Errorf("got %q, want one embed", got)
+	}
+}
+
+// Verifies that templates in the shortcode directory override and extend the built-ins.
+func TestConvert_ShortcodeTemplates(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "figure.html"), []byte(`<figure class="custom"><img src="{{.src}}"></figure>`), 0o644)
+	os.WriteFile(filepath.Join(dir, "aside.html"), []byte(`<aside>{{.text}}</aside>`), 0o644)
+
+	c, err := parser.NewConverter(parser.Options{ShortcodeDir: dir})
+	if err != nil {
+		t.Fatalf("NewConverter error: %v", err)
+	}
+
+	out, err := c.Convert(parser.Document{Name: "post.md", FirstLine: 1, Body: []byte("{{< figure src=\"/a.png\" >}}\n\n{{< aside text=\"Psst\" >}}")})
+	if err != nil {
+		t.Fatalf("Convert error: %v", err)
+	}
+	got := string(out)
+	if !strings.Contains(got, `<figure class="custom">`) {
+		t.Errorf("got %q, want overridden figure template", got)
+	}
+	if !strings.Contains(got, `<aside>Psst</aside>`) {
+		t.Errorf("got %q, want custom aside shortcode", got)
+	}
+
+	if _, err := c.Convert(parser.Document{Name: "post.md", FirstLine: 1, Body: []byte(`{{< figure caption="x" >}}`)}); err == nil {
+		t.Error("overridden built-in should keep its required arguments")
+	}
+}
@@ -0,0 +1,232 @@
+package renderer
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/integralist/integralist.co.uk/internal/model"
+)
+
+// siteName is the name the site publishes under in structured data.
+const siteName = "integralist"
+
+// The types below are the subset of schema.org used in each page's JSON-LD.
+// Every page gets a single script with an @graph of one or more of them.
+
+type ldGraph struct {
+	Context string `json:"@context"`
+	Graph   []any  `json:"@graph"`
+}
+
+type ldBlogPosting struct {
+	Type             string         `json:"@type"`
+	Headline         string         `json:"headline"`
+	Description      string         `json:"description,omitempty"`
+	URL              string         `json:"url"`
+	Image            string         `json:"image,omitempty"`
+	DatePublished    string         `json:"datePublished"`
+	DateModified     string         `json:"dateModified"`
+	Author           *ldPerson      `json:"author,omitempty"`
+	Keywords         string         `json:"keywords,omitempty"`
+	WordCount        int            `json:"wordCount"`
+	TimeRequired     string         `json:"timeRequired"`
+	Publisher        ldOrganization `json:"publisher"`
+	MainEntityOfPage ldRef          `json:"mainEntityOfPage"`
+}
+
+type ldPerson struct {
+	Type     string   `json:"@type"`
+	Name     string   `json:"name"`
+	JobTitle string   `json:"jobTitle,omitempty"`
+	Image    string   `json:"image,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	SameAs   []string `json:"sameAs,omitempty"`
+}
+
+type ldOrganization struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// ldRef refers to another node by its @id.
+type ldRef struct {
+	Type string `json:"@type"`
+	ID   string `json:"@id"`
+}
+
+type ldBreadcrumbList struct {
+	Type            string       `json:"@type"`
+	ItemListElement []ldListItem `json:"itemListElement"`
+}
+
+type ldListItem struct {
+	Type     string `json:"@type"`
+	Position int    `json:"position"`
+	Name     string `json:"name,omitempty"`
+	Item     string `json:"item,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+type ldWebSite struct {
+	Type            string          `json:"@type"`
+	Name            string          `json:"name"`
+	URL             string          `json:"url"`
+	Publisher       ldOrganization  `json:"publisher"`
+	PotentialAction *ldSearchAction `json:"potentialAction,omitempty"`
+}
+
+type ldSearchAction struct {
+	Type       string       `json:"@type"`
+	Target     ldEntryPoint `json:"target"`
+	QueryInput string       `json:"query-input"`
+}
+
+type ldEntryPoint struct {
+	Type        string `json:"@type"`
+	URLTemplate string `json:"urlTemplate"`
+}
+
+type ldCollectionPage struct {
+	Type        string     `json:"@type"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	URL         string     `json:"url"`
+	MainEntity  ldItemList `json:"mainEntity"`
+}
+
+type ldItemList struct {
+	Type            string       `json:"@type"`
+	NumberOfItems   int          `json:"numberOfItems"`
+	ItemListElement []ldListItem `json:"itemListElement"`
+}
+
+type ldProfilePage struct {
+	Type         string   `json:"@type"`
+	Name         string   `json:"name"`
+	URL          string   `json:"url"`
+	DateModified string   `json:"dateModified,omitempty"`
+	MainEntity   ldPerson `json:"mainEntity"`
+}
+
+func publisher(site *model.Site) ldOrganization {
+	return ldOrganization{Type: "Organization", Name: siteName, URL: site.BaseURL + "/"}
+}
+
+func blogPosting(post *model.Post, site *model.Site) ldBlogPosting {
+	url := site.BaseURL + post.URL
+	ld := ldBlogPosting{
+		Type:             "BlogPosting",
+		Headline:         post.Title,
+		Description:      post.Description,
+		URL:              url,
+		DatePublished:    post.Date.Format(time.RFC3339),
+		DateModified:     cmp.Or(post.Updated, post.Date).Format(time.RFC3339),
+		Keywords:         strings.Join(post.Keywords, ", "),
+		WordCount:        post.WordCount,
+		TimeRequired:     fmt.Sprintf("PT%dM", post.ReadingTime),
+		Publisher:        publisher(site),
+		MainEntityOfPage: ldRef{Type: "WebPage", ID: url},
+	}
+	if post.Author != "" {
+		ld.Author = &ldPerson{Type: "Person", Name: post.Author}
+	}
+	if image := cmp.Or(post.Image, post.CardImage); image != "" {
+		ld.Image = site.BaseURL + image
+	}
+	return ld
+}
+
+// breadcrumbList returns the trail from the home page through crumbs, whose
+// URLs are relative to the site root.
+func breadcrumbList(site *model.Site, crumbs ...model.Breadcrumb) ldBreadcrumbList {
+	items := []ldListItem{{Type: "ListItem", Position: 1, Name: "Home", Item: site.BaseURL + "/"}}
+	for _, c := range crumbs {
+		items = append(items, ldListItem{
+			Type:     "ListItem",
+			Position: len(items) + 1,
+			Name:     c.Title,
+			Item:     site.BaseURL + c.URL,
+		})
+	}
+	return ldBreadcrumbList{Type: "BreadcrumbList", ItemListElement: items}
+}
+
+// webSite describes the site itself. searchURL, if set, is a URL template
+// containing {search_term_string}.
+func webSite(site *model.Site, searchURL string) ldWebSite {
+	ld := ldWebSite{
+		Type:      "WebSite",
+		Name:      siteName,
+		URL:       site.BaseURL + "/",
+		Publisher: publisher(site),
+	}
+	if searchURL != "" {
+		ld.PotentialAction = &ldSearchAction{
+			Type:       "SearchAction",
+			Target:     ldEntryPoint{Type: "EntryPoint", URLTemplate: searchURL},
+			QueryInput: "required name=search_term_string",
+		}
+	}
+	return ld
+}
+
+// collectionPage describes the page named name that lists tag's posts.
+func collectionPage(name string, tag *model.Tag, site *model.Site) ldCollectionPage {
+	items := make([]ldListItem, 0, len(tag.Posts))
+	for i, post := range tag.Posts {
+		items = append(items, ldListItem{
+			Type:     "ListItem",
+			Position: i + 1,
+			URL:      site.BaseURL + post.URL,
+		})
+	}
+	return ldCollectionPage{
+		Type:        "CollectionPage",
+		Name:        name,
+		Description: tag.Description,
+		URL:         site.BaseURL + tag.URL,
+		MainEntity: ldItemList{
+			Type:            "ItemList",
+			NumberOfItems:   len(items),
+			ItemListElement: items,
+		},
+	}
+}
+
+func profilePage(page *model.Page, site *model.Site) ldProfilePage {
+	url := site.BaseURL + page.URL
+	ld := ldProfilePage{
+		Type: "ProfilePage",
+		Name: page.Title,
+		URL:  url,
+		MainEntity: ldPerson{
+			Type:     "Person",
+			Name:     page.Person.Name,
+			JobTitle: page.Person.JobTitle,
+			URL:      url,
+			SameAs:   page.Person.SameAs,
+		},
+	}
+	if !page.Updated.IsZero() {
+		ld.DateModified = page.Updated.Format(time.RFC3339)
+	}
+	if page.Image != "" {
+		ld.MainEntity.Image = site.BaseURL + page.Image
+	}
+	return ld
+}
+
+// jsonLD renders nodes as a JSON-LD script. encoding/json escapes <, > and &,
+// so the output can't close the script element early.
+func jsonLD(nodes ...any) template.HTML {
+	data, err := json.Marshal(ldGraph{Context: "https://schema.org", Graph: nodes})
+	if err != nil {
+		return ""
+	}
+	return template.HTML(`<script type="application/ld+json">` + string(data) + `</script>`)
+}
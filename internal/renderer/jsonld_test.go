@@ -0,0 +1,121 @@
+package renderer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/integralist/integralist.co.uk/internal/model"
+	"github.com/integralist/integralist.co.uk/internal/renderer"
+)
+
+var update = flag.Bool("update", false, "rewrite the JSON-LD fixtures in testdata/jsonld")
+
+// Verifies each page type's structured data against the fixtures in
+// testdata/jsonld. Run with -update to rewrite them after a deliberate change.
+func TestJSONLD(t *testing.T) {
+	r, err := renderer.New(templateDir, renderer.WithSearchURL("https://duckduckgo.com/?q=site%3Awww.integralist.co.uk+{search_term_string}"))
+	if err != nil {
+		t.Fatalf("failed to create renderer: %v", err)
+	}
+
+	site := testSite()
+	post := site.Posts[0]
+	post.Image = "/assets/img/hero.jpg"
+	post.Keywords = []string{"go", "static site generator"}
+	post.ReadingTime = 3
+	post.WordCount = 512
+	post.Updated = time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	projects := &model.Page{Title: "Projects", URL: "/projects/", Section: true}
+	child := &model.Page{Title: "Foo", URL: "/projects/foo/", Parent: projects}
+	resume := &model.Page{
+		Title:  "Resume",
+		URL:    "/resume/",
+		Image:  "/assets/img/profile.jpg",
+		Schema: "ProfilePage",
+		Person: &model.Person{
+			Name:     "Mark McDonnell",
+			JobTitle: "Staff Software Engineer",
+			SameAs:   []string{"https://github.com/integralist"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		render func() ([]byte, error)
+	}{
+		{"home", func() ([]byte, error) { return r.RenderHome(site) }},
+		{"post", func() ([]byte, error) { return r.RenderPost(post, site) }},
+		{"page", func() ([]byte, error) { return r.RenderPage(child, site) }},
+		{"profile", func() ([]byte, error) { return r.RenderPage(resume, site) }},
+		{"tag", func() ([]byte, error) { return r.RenderTermPage(site.Taxonomies[0], site.Tags[0], site) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tt.render()
+			if err != nil {
+				t.Fatalf("render error: %v", err)
+			}
+			got := extractJSONLD(t, out)
+
+			file := filepath.Join("testdata", "jsonld", tt.name+".json")
+			if *update {
+				if err := os.WriteFile(file, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("reading fixture: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("JSON-LD does not match %s:\n%s", file, got)
+			}
+		})
+	}
+}
+
+// extractJSONLD returns the page's JSON-LD, indented for comparison, after
+// checking that it is valid JSON with a schema.org context and that every
+// node has a type.
+func extractJSONLD(t *testing.T, html []byte) []byte {
+	t.Helper()
+	const open = `<script type="application/ld+json">`
+	_, rest, ok := strings.Cut(string(html), open)
+	if !ok {
+		t.Fatal("page has no JSON-LD script")
+	}
+	src, _, _ := strings.Cut(rest, "</script>")
+	if strings.Contains(rest[len(src):], open) {
+		t.Error("page has more than one JSON-LD script")
+	}
+
+	var doc struct {
+		Context string           `json:"@context"`
+		Graph   []map[string]any `json:"@graph"`
+	}
+	if err := json.Unmarshal([]byte(src), &doc); err != nil {
+		t.Fatalf("invalid JSON-LD: %v", err)
+	}
+	if doc.Context != "https://schema.org" {
+		t.Errorf("@context = %q, want https://schema.org", doc.Context)
+	}
+	for i, node := range doc.Graph {
+		if node["@type"] == nil {
+			t.Errorf("@graph[%d] has no @type", i)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(src), "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
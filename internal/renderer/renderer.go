@@ -2,13 +2,18 @@ package renderer
 
 import (
 	"bytes"
-	"encoding/json"
+	"cmp"
+	"errors"
 	"fmt"
 	"html/template"
+	"io/fs"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/integralist/integralist.co.uk/internal/asset"
+	"github.com/integralist/integralist.co.uk/internal/bundle"
 	"github.com/integralist/integralist.co.uk/internal/model"
 )
 
@@ -19,17 +24,62 @@ type TagWithColor struct {
 	URL   string
 }
 
+// TermLinks are a post's terms in one taxonomy besides tags.
+type TermLinks struct {
+	Taxonomy *model.Taxonomy
+	Terms    []TagWithColor
+}
+
 // Renderer parses and executes HTML templates.
 type Renderer struct {
-	home    *template.Template
-	post    *template.Template
-	page    *template.Template
-	tag     *template.Template
-	tagsIdx *template.Template
+	assets   asset.Manifest
+	bundles  *bundle.Registry
+	home     *template.Template
+	notFound *template.Template
+	post     *template.Template
+	page     *template.Template
+	// searchURL is the site search URL template advertised in the home
+	// page's structured data.
+	searchURL string
+	tag       *template.Template
+	tagsIdx   *template.Template
+}
+
+// Option configures a Renderer.
+type Option func(*Renderer)
+
+// WithAssets sets the manifest the asset template function resolves paths
+// against. Without it, asset "css/style.css" returns /assets/css/style.css.
+func WithAssets(m asset.Manifest) Option {
+	return func(r *Renderer) {
+		r.assets = m
+	}
+}
+
+// WithBundles sets the registry used to resolve each post's js and css
+// bundles into script and stylesheet tags.
+func WithBundles(b *bundle.Registry) Option {
+	return func(r *Renderer) {
+		r.bundles = b
+	}
+}
+
+// WithSearchURL advertises a site search in the home page's structured data.
+// u is a URL template containing {search_term_string}.
+func WithSearchURL(u string) Option {
+	return func(r *Renderer) {
+		r.searchURL = u
+	}
 }
 
 // New creates a Renderer by parsing templates from templateDir.
-func New(templateDir string) (*Renderer, error) {
+func New(templateDir string, opts ...Option) (*Renderer, error) {
+	r := &Renderer{}
+	for _, opt := range opts {
+		opt(r)
+	}
+	funcs := template.FuncMap{"asset": r.assetURL}
+
 	shared := []string{
 		filepath.Join(templateDir, "base.html"),
 		filepath.Join(templateDir, "header.html"),
@@ -38,7 +88,7 @@ func New(templateDir string) (*Renderer, error) {
 
 	parse := func(contentTemplate string) (*template.Template, error) {
 		files := append(append([]string{}, shared...), filepath.Join(templateDir, contentTemplate))
-		return template.ParseFiles(files...)
+		return template.New(filepath.Base(files[0])).Funcs(funcs).ParseFiles(files...)
 	}
 
 	home, err := parse("home.html")
@@ -61,28 +111,51 @@ func New(templateDir string) (*Renderer, error) {
 	if err != nil {
 		return nil, fmt.Errorf("parsing tags index template: %w", err)
 	}
+	// The 404 template is optional; without it no 404 page is rendered.
+	if _, err := os.Stat(filepath.Join(templateDir, "404.html")); err == nil {
+		r.notFound, err = parse("404.html")
+		if err != nil {
+			return nil, fmt.Errorf("parsing 404 template: %w", err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("parsing 404 template: %w", err)
+	}
+
+	r.home = home
+	r.post = post
+	r.page = page
+	r.tag = tag
+	r.tagsIdx = tagsIdx
+	return r, nil
+}
 
-	return &Renderer{
-		home:    home,
-		post:    post,
-		page:    page,
-		tag:     tag,
-		tagsIdx: tagsIdx,
-	}, nil
+// assetURL backs the asset template function, resolving a path relative to
+// the assets directory to its (possibly fingerprinted) URL.
+func (r *Renderer) assetURL(file string) (string, error) {
+	if r.assets == nil {
+		return "/assets/" + strings.TrimPrefix(file, "/"), nil
+	}
+	return r.assets.URL(file)
 }
 
 type baseData struct {
-	ArticleTags   []string
-	Author        string
-	BaseURL       string
-	CanonicalURL  string
-	Description   string
+	ArticleTags  []string
+	Assets       bundle.Assets
+	Author       string
+	BaseURL      string
+	CanonicalURL string
+	Description  string
+	// FeedTitle and FeedURL, when set, advertise a feed of the page's posts
+	// at FeedURL + "rss.xml".
+	FeedTitle     string
+	FeedURL       string
 	Image         string
-	JS            []string
 	JSONLD        template.HTML
 	Keywords      string
 	MarkdownURL   string
+	ModifiedTime  string
 	NavPages      []*model.Page
+	NoIndex       bool
 	OGType        string
 	PublishedTime string
 	Title         string
@@ -92,7 +165,7 @@ type baseData struct {
 func newBaseData(site *model.Site) baseData {
 	return baseData{
 		BaseURL:  site.BaseURL,
-		NavPages: site.Pages,
+		NavPages: site.NavPages(),
 		OGType:   "website",
 		Year:     time.Now().Year(),
 	}
@@ -111,34 +184,50 @@ func (r *Renderer) RenderHome(site *model.Site) ([]byte, error) {
 	data.Title = ""
 	data.Description = "integralist.co.uk"
 	data.CanonicalURL = site.BaseURL + "/"
+	data.JSONLD = jsonLD(webSite(site, r.searchURL))
 	return execute(r.home, data)
 }
 
 func (r *Renderer) RenderPost(post *model.Post, site *model.Site) ([]byte, error) {
 	tagsWithColors := resolveTagColors(post.Tags, site.Tags)
 
+	var terms []TermLinks
+	for _, tax := range site.Taxonomies {
+		if tax.Key == model.TagsKey {
+			continue
+		}
+		if links := resolveTagColors(tax.PostTerms(post), tax.Terms); len(links) > 0 {
+			terms = append(terms, TermLinks{Taxonomy: tax, Terms: links})
+		}
+	}
+
 	data := struct {
 		baseData
 		Post           *model.Post
 		TagsWithColors []TagWithColor
+		Terms          []TermLinks
 	}{
 		baseData:       newBaseData(site),
 		Post:           post,
 		TagsWithColors: tagsWithColors,
+		Terms:          terms,
 	}
 	data.ArticleTags = post.Tags
 	data.Author = post.Author
 	data.CanonicalURL = site.BaseURL + post.URL
 	data.Description = post.Description
-	data.JS = post.JS
-	if post.Image != "" {
-		data.Image = site.BaseURL + post.Image
+	data.Assets = r.bundles.Assets(post.JS, post.CSS)
+	if image := cmp.Or(post.Image, post.CardImage); image != "" {
+		data.Image = site.BaseURL + image
 	}
-	data.JSONLD = buildArticleJSONLD(post, site)
+	data.JSONLD = jsonLD(blogPosting(post, site), breadcrumbList(site, model.Breadcrumb{Title: post.Title, URL: post.URL}))
 	data.Keywords = strings.Join(post.Keywords, ", ")
 	data.MarkdownURL = "index.md"
 	data.OGType = "article"
 	data.PublishedTime = post.Date.Format(time.RFC3339)
+	if !post.Updated.IsZero() {
+		data.ModifiedTime = post.Updated.Format(time.RFC3339)
+	}
 	data.Title = post.Title
 	return execute(r.post, data)
 }
@@ -156,37 +245,91 @@ func (r *Renderer) RenderPage(page *model.Page, site *model.Site) ([]byte, error
 	data.Keywords = strings.Join(page.Keywords, ", ")
 	data.CanonicalURL = site.BaseURL + page.URL
 	data.MarkdownURL = "index.md"
+	if page.Schema == "ProfilePage" {
+		data.JSONLD = jsonLD(profilePage(page, site), breadcrumbList(site, page.Breadcrumbs()...))
+	} else {
+		data.JSONLD = jsonLD(breadcrumbList(site, page.Breadcrumbs()...))
+	}
 	return execute(r.page, data)
 }
 
-func (r *Renderer) RenderTagPage(tag *model.Tag, site *model.Site) ([]byte, error) {
+// RenderTermPage renders the page listing the posts with term, one of the
+// terms of tax. The template is tag.html.
+func (r *Renderer) RenderTermPage(tax *model.Taxonomy, term *model.Tag, site *model.Site) ([]byte, error) {
 	data := struct {
 		baseData
-		Tag *model.Tag
+		Tag      *model.Tag
+		Taxonomy *model.Taxonomy
 	}{
 		baseData: newBaseData(site),
-		Tag:      tag,
+		Tag:      term,
+		Taxonomy: tax,
 	}
-	data.Title = "Posts tagged \"" + tag.Name + "\""
-	data.CanonicalURL = site.BaseURL + tag.URL
+	data.Title = tax.TermTitle(term)
+	data.CanonicalURL = site.BaseURL + term.URL
+	data.Description = term.Description
+	data.FeedTitle = "integralist: " + data.Title
+	data.FeedURL = site.BaseURL + term.URL
 	data.MarkdownURL = "index.md"
+	data.JSONLD = jsonLD(collectionPage(data.Title, term, site), breadcrumbList(site, termBreadcrumbs(tax, term)...))
 	return execute(r.tag, data)
 }
 
-func (r *Renderer) RenderTagsIndex(site *model.Site) ([]byte, error) {
+// termBreadcrumbs returns the trail from the taxonomy's index to term,
+// through its first parent at each level.
+func termBreadcrumbs(tax *model.Taxonomy, term *model.Tag) []model.Breadcrumb {
+	var crumbs []model.Breadcrumb
+	seen := make(map[*model.Tag]bool)
+	for t := term; t != nil && !seen[t]; {
+		seen[t] = true
+		crumbs = append([]model.Breadcrumb{{Title: t.Name, URL: t.URL}}, crumbs...)
+		if len(t.Parents) == 0 {
+			break
+		}
+		t = t.Parents[0]
+	}
+	return append([]model.Breadcrumb{{Title: tax.Title, URL: tax.URL()}}, crumbs...)
+}
+
+// RenderTaxonomyIndex renders the page listing every term of tax. The
+// template is tags.html.
+func (r *Renderer) RenderTaxonomyIndex(tax *model.Taxonomy, site *model.Site) ([]byte, error) {
 	data := struct {
 		baseData
-		Tags []*model.Tag
+		Tags     []*model.Tag
+		Taxonomy *model.Taxonomy
 	}{
 		baseData: newBaseData(site),
-		Tags:     site.Tags,
+		Tags:     tax.Terms,
+		Taxonomy: tax,
 	}
-	data.Title = "Tags"
-	data.CanonicalURL = site.BaseURL + "/tags/"
+	data.Title = tax.Title
+	data.CanonicalURL = site.BaseURL + tax.URL()
 	data.MarkdownURL = "index.md"
 	return execute(r.tagsIdx, data)
 }
 
+// HasNotFound reports whether the template directory has a 404 template.
+func (r *Renderer) HasNotFound() bool {
+	return r.notFound != nil
+}
+
+// RenderNotFound renders the 404 page. slugsURL is the JSON list of post and
+// page slugs the page uses to suggest what the reader may have meant. It must
+// only be called when HasNotFound is true.
+func (r *Renderer) RenderNotFound(site *model.Site, slugsURL string) ([]byte, error) {
+	data := struct {
+		baseData
+		SlugsURL string
+	}{
+		baseData: newBaseData(site),
+		SlugsURL: slugsURL,
+	}
+	data.Title = "Page not found"
+	data.NoIndex = true
+	return execute(r.notFound, data)
+}
+
 func execute(tmpl *template.Template, data any) ([]byte, error) {
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
@@ -195,32 +338,6 @@ func execute(tmpl *template.Template, data any) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func buildArticleJSONLD(post *model.Post, site *model.Site) template.HTML {
-	ld := map[string]any{
-		"@context":      "https://schema.org",
-		"@type":         "Article",
-		"headline":      post.Title,
-		"datePublished": post.Date.Format(time.RFC3339),
-		"description":   post.Description,
-		"url":           site.BaseURL + post.URL,
-	}
-	if post.Author != "" {
-		ld["author"] = map[string]string{
-			"@type": "Person",
-			"name":  post.Author,
-		}
-	}
-	if post.Image != "" {
-		ld["image"] = site.BaseURL + post.Image
-	}
-
-	data, err := json.Marshal(ld)
-	if err != nil {
-		return ""
-	}
-	return template.HTML(`<script type="application/ld+json">` + string(data) + `</script>`)
-}
-
 func buildTagColorMap(allTags []*model.Tag) map[string]TagWithColor {
 	m := make(map[string]TagWithColor, len(allTags))
 	for _, t := range allTags {
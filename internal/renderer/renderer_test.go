@@ -43,7 +43,13 @@ func testSite() *model.Site {
 		{Name: "ssg", Slug: "ssg", Posts: posts, URL: "/tags/ssg/", Color: "#D4A04A"},
 	}
 
-	return &model.Site{BaseURL: "https://www.integralist.co.uk", Posts: posts, Pages: pages, Tags: tags}
+	return &model.Site{
+		BaseURL:    "https://www.integralist.co.uk",
+		Posts:      posts,
+		Pages:      pages,
+		Tags:       tags,
+		Taxonomies: []*model.Taxonomy{{Key: model.TagsKey, Singular: "tag", Title: "Tags", Terms: tags}},
+	}
 }
 
 func TestRenderHome_ContainsPostLinks(t *testing.T) {
@@ -69,6 +75,40 @@ func TestRenderHome_ContainsPostLinks(t *testing.T) {
 	}
 }
 
+// Verifies that listings show a post's <!--more--> excerpt in place of its
+// description.
+func TestRenderHome_Excerpt(t *testing.T) {
+	r, err := renderer.New(templateDir)
+	if err != nil {
+		t.Fatalf("failed to create renderer: %v", err)
+	}
+
+	site := testSite()
+	out, err := r.RenderHome(site)
+	if err != nil {
+		t.Fatalf("RenderHome error: %v", err)
+	}
+	if !strings.Contains(string(out), "<p>The first post</p>") {
+		t.Error("home page missing description")
+	}
+
+	site.Posts[0].Summary = template.HTML("<p>An <em>excerpt</em>.</p>")
+	site.Posts[0].Excerpt = true
+	for name, render := range map[string]func() ([]byte, error){
+		"home": func() ([]byte, error) { return r.RenderHome(site) },
+		"tag":  func() ([]byte, error) { return r.RenderTermPage(site.Taxonomies[0], site.Tags[0], site) },
+	} {
+		out, err := render()
+		if err != nil {
+			t.Fatalf("%s: render error: %v", name, err)
+		}
+		html := string(out)
+		if !strings.Contains(html, `<div class="post-excerpt"><p>An <em>excerpt</em>.</p></div>`) || strings.Contains(html, "<p>The first post</p>") {
+			t.Errorf("%s page should show the excerpt instead of the description", name)
+		}
+	}
+}
+
 func TestRenderPost_ContainsTitleAndMarkdownLink(t *testing.T) {
 	r, err := renderer.New(templateDir)
 	if err != nil {
@@ -140,9 +180,9 @@ func TestRenderTagPage_ContainsMarkdownAlternateLink(t *testing.T) {
 	}
 
 	site := testSite()
-	out, err := r.RenderTagPage(site.Tags[0], site)
+	out, err := r.RenderTermPage(site.Taxonomies[0], site.Tags[0], site)
 	if err != nil {
-		t.Fatalf("RenderTagPage error: %v", err)
+		t.Fatalf("RenderTermPage error: %v", err)
 	}
 	html := string(out)
 	if !strings.Contains(html, `type="text/markdown"`) {
@@ -158,9 +198,9 @@ func TestRenderTagsIndex_ContainsMarkdownAlternateLink(t *testing.T) {
 	}
 
 	site := testSite()
-	out, err := r.RenderTagsIndex(site)
+	out, err := r.RenderTaxonomyIndex(site.Taxonomies[0], site)
 	if err != nil {
-		t.Fatalf("RenderTagsIndex error: %v", err)
+		t.Fatalf("RenderTaxonomyIndex error: %v", err)
 	}
 	html := string(out)
 	if !strings.Contains(html, `type="text/markdown"`) {
@@ -299,6 +339,29 @@ func TestRenderPost_WithImage_RendersLinkedImage(t *testing.T) {
 	}
 }
 
+// Verifies that a generated card stands in for a missing image in meta tags,
+// without being shown as a hero image.
+func TestRenderPost_CardImage(t *testing.T) {
+	r, err := renderer.New(templateDir)
+	if err != nil {
+		t.Fatalf("failed to create renderer: %v", err)
+	}
+
+	site := testSite()
+	site.Posts[0].CardImage = "/posts/first-post/og.png"
+	out, err := r.RenderPost(site.Posts[0], site)
+	if err != nil {
+		t.Fatalf("RenderPost error: %v", err)
+	}
+	html := string(out)
+	if !strings.Contains(html, `<meta property="og:image" content="https://www.integralist.co.uk/posts/first-post/og.png">`) {
+		t.Error("post page missing card og:image")
+	}
+	if strings.Contains(html, `class="post-hero"`) {
+		t.Error("card rendered as a hero image")
+	}
+}
+
 // Verifies that a post without an image does not render a hero image.
 func TestRenderPost_WithoutImage_NoHeroImage(t *testing.T) {
 	r, err := renderer.New(templateDir)
@@ -340,7 +403,7 @@ func TestRenderPost_WithoutImage_UsesSummaryCard(t *testing.T) {
 	}
 }
 
-// Verifies that post pages include JSON-LD structured data with Article schema.
+// Verifies that post pages include JSON-LD structured data with BlogPosting schema.
 func TestRenderPost_ContainsJSONLD(t *testing.T) {
 	r, err := renderer.New(templateDir)
 	if err != nil {
@@ -357,8 +420,8 @@ func TestRenderPost_ContainsJSONLD(t *testing.T) {
 	if !strings.Contains(html, `application/ld+json`) {
 		t.Error("post page missing JSON-LD script tag")
 	}
-	if !strings.Contains(html, `"@type":"Article"`) {
-		t.Error("post page JSON-LD missing Article type")
+	if !strings.Contains(html, `"@type":"BlogPosting"`) {
+		t.Error("post page JSON-LD missing BlogPosting type")
 	}
 	if !strings.Contains(html, `"headline":"First Post"`) {
 		t.Error("post page JSON-LD missing headline")
@@ -377,8 +440,46 @@ func TestRenderPost_ContainsJSONLD(t *testing.T) {
 	}
 }
 
-// Verifies that non-article pages do not include Article JSON-LD.
-func TestRenderHome_NoArticleJSONLD(t *testing.T) {
+// Verifies that an updated post shows its updated date and reports it in
+// meta tags and JSON-LD, and that an unchanged post falls back to its date.
+func TestRenderPost_Updated(t *testing.T) {
+	r, err := renderer.New(templateDir)
+	if err != nil {
+		t.Fatalf("failed to create renderer: %v", err)
+	}
+
+	site := testSite()
+	out, err := r.RenderPost(site.Posts[0], site)
+	if err != nil {
+		t.Fatalf("RenderPost error: %v", err)
+	}
+	html := string(out)
+	if strings.Contains(html, "article:modified_time") || strings.Contains(html, `class="updated"`) {
+		t.Error("post without an updated date shows one")
+	}
+	if !strings.Contains(html, `"dateModified":"2026-04-12T00:00:00Z"`) {
+		t.Error("JSON-LD dateModified should fall back to the publish date")
+	}
+
+	site.Posts[0].Updated = time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	out, err = r.RenderPost(site.Posts[0], site)
+	if err != nil {
+		t.Fatalf("RenderPost error: %v", err)
+	}
+	html = string(out)
+	for _, want := range []string{
+		`<span class="updated">Updated <time datetime="2026-05-01">May 1, 2026</time></span>`,
+		`<meta property="article:modified_time" content="2026-05-01T00:00:00Z">`,
+		`"dateModified":"2026-05-01T00:00:00Z"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("post page missing %q", want)
+		}
+	}
+}
+
+// Verifies that non-article pages do not include BlogPosting JSON-LD.
+func TestRenderHome_NoBlogPostingJSONLD(t *testing.T) {
 	r, err := renderer.New(templateDir)
 	if err != nil {
 		t.Fatalf("failed to create renderer: %v", err)
@@ -390,8 +491,8 @@ func TestRenderHome_NoArticleJSONLD(t *testing.T) {
 		t.Fatalf("RenderHome error: %v", err)
 	}
 	html := string(out)
-	if strings.Contains(html, `"@type":"Article"`) {
-		t.Error("home page should not have Article JSON-LD")
+	if strings.Contains(html, `"@type":"BlogPosting"`) {
+		t.Error("home page should not have BlogPosting JSON-LD")
 	}
 }
 
@@ -420,9 +521,9 @@ func TestRenderTagPage_ListsPosts(t *testing.T) {
 	}
 
 	site := testSite()
-	out, err := r.RenderTagPage(site.Tags[0], site)
+	out, err := r.RenderTermPage(site.Taxonomies[0], site.Tags[0], site)
 	if err != nil {
-		t.Fatalf("RenderTagPage error: %v", err)
+		t.Fatalf("RenderTermPage error: %v", err)
 	}
 	html := string(out)
 	if !strings.Contains(html, "First Post") {
@@ -433,6 +534,37 @@ func TestRenderTagPage_ListsPosts(t *testing.T) {
 	}
 }
 
+func TestRenderTagPage_DescriptionAndHierarchy(t *testing.T) {
+	r, err := renderer.New(templateDir)
+	if err != nil {
+		t.Fatalf("failed to create renderer: %v", err)
+	}
+
+	site := testSite()
+	tag := site.Tags[0]
+	parent := &model.Tag{Name: "Programming", Slug: "programming", URL: "/tags/programming/", Children: []*model.Tag{tag}}
+	tag.Parents = []*model.Tag{parent}
+	tag.Content = "<p>Posts about <em>Go</em>.</p>"
+	tag.Description = "Posts about Go."
+
+	out, err := r.RenderTermPage(site.Taxonomies[0], tag, site)
+	if err != nil {
+		t.Fatalf("RenderTermPage error: %v", err)
+	}
+	html := string(out)
+	for _, want := range []string{
+		`<div class="tag-description"><p>Posts about <em>Go</em>.</p></div>`,
+		`<meta name="description" content="Posts about Go.">`,
+		`href="/tags/programming/"`,
+		`"description":"Posts about Go."`,
+		`{"@type":"ListItem","position":3,"name":"Programming","item":"https://www.integralist.co.uk/tags/programming/"}`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("tag page missing %s", want)
+		}
+	}
+}
+
 func TestRenderTagsIndex_ListsAllTags(t *testing.T) {
 	r, err := renderer.New(templateDir)
 	if err != nil {
@@ -440,9 +572,9 @@ func TestRenderTagsIndex_ListsAllTags(t *testing.T) {
 	}
 
 	site := testSite()
-	out, err := r.RenderTagsIndex(site)
+	out, err := r.RenderTaxonomyIndex(site.Taxonomies[0], site)
 	if err != nil {
-		t.Fatalf("RenderTagsIndex error: %v", err)
+		t.Fatalf("RenderTaxonomyIndex error: %v", err)
 	}
 	html := string(out)
 	if !strings.Contains(html, "/tags/go/") {
@@ -452,3 +584,83 @@ func TestRenderTagsIndex_ListsAllTags(t *testing.T) {
 		t.Error("tags index missing ssg tag link")
 	}
 }
+
+func TestRenderNotFound(t *testing.T) {
+	r, err := renderer.New(templateDir)
+	if err != nil {
+		t.Fatalf("failed to create renderer: %v", err)
+	}
+
+	out, err := r.RenderNotFound(testSite(), "/slugs.json")
+	if err != nil {
+		t.Fatalf("RenderNotFound error: %v", err)
+	}
+	html := string(out)
+	for _, want := range []string{
+		"<title>Page not found | integralist</title>",
+		`<meta name="robots" content="noindex">`,
+		`href="/about/"`,
+		"fetch('\\/slugs.json')",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("404 page missing %q", want)
+		}
+	}
+	if strings.Contains(html, `rel="canonical"`) || strings.Contains(html, `type="text/markdown"`) {
+		t.Error("404 page should not have a canonical URL or Markdown companion")
+	}
+}
+
+func TestRenderTermPage_OtherTaxonomy(t *testing.T) {
+	r, err := renderer.New(templateDir)
+	if err != nil {
+		t.Fatalf("failed to create renderer: %v", err)
+	}
+
+	site := testSite()
+	post := site.Posts[0]
+	post.Terms = map[string][]string{"series": {"Go Concurrency"}}
+	term := &model.Tag{Name: "Go Concurrency", Slug: "go-concurrency", Posts: site.Posts, URL: "/series/go-concurrency/", Color: "#6BA397"}
+	series := &model.Taxonomy{Key: "series", Singular: "series", Title: "Series", Terms: []*model.Tag{term}}
+	site.Taxonomies = append(site.Taxonomies, series)
+
+	checks := []struct {
+		name   string
+		render func() ([]byte, error)
+		want   []string
+	}{
+		{
+			name:   "term page",
+			render: func() ([]byte, error) { return r.RenderTermPage(series, term, site) },
+			want: []string{
+				`<title>Posts in series &#34;Go Concurrency&#34; | integralist</title>`,
+				`<h1>Posts in series <span`,
+				`href="https://www.integralist.co.uk/series/go-concurrency/rss.xml"`,
+				`{"@type":"ListItem","position":2,"name":"Series","item":"https://www.integralist.co.uk/series/"}`,
+			},
+		},
+		{
+			name:   "index",
+			render: func() ([]byte, error) { return r.RenderTaxonomyIndex(series, site) },
+			want:   []string{`<h1>Series</h1>`, `href="/series/go-concurrency/"`},
+		},
+		{
+			name:   "post",
+			render: func() ([]byte, error) { return r.RenderPost(post, site) },
+			want:   []string{`<p class="tag-list post-terms">Series`, `<a href="/series/go-concurrency/" class="tag" style="background-color: #6BA397">Go Concurrency</a>`},
+		},
+	}
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			out, err := c.render()
+			if err != nil {
+				t.Fatalf("render error: %v", err)
+			}
+			for _, want := range c.want {
+				if !strings.Contains(string(out), want) {
+					t.Errorf("missing %s", want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// redirect is one rule from a Netlify _redirects file.
+type redirect struct {
+	from   pattern
+	to     string
+	status int
+	force  bool // apply even when a file exists at the path ("!" suffix)
+}
+
+// headerRule is one path block from a Netlify _headers file.
+type headerRule struct {
+	path    pattern
+	headers [][2]string
+}
+
+// pattern is a Netlify path pattern: segments may be :placeholders and the
+// last may be a * splat.
+type pattern []string
+
+func parsePattern(s string) pattern {
+	return pattern(strings.Split(trimSlash(s), "/"))
+}
+
+// match reports whether p matches urlPath, returning the values bound to
+// placeholders and the splat (under "splat").
+func (p pattern) match(urlPath string) (map[string]string, bool) {
+	segs := strings.Split(trimSlash(urlPath), "/")
+	vars := make(map[string]string)
+	for i, want := range p {
+		if want == "*" && i == len(p)-1 {
+			splat := strings.Join(segs[i:], "/")
+			if splat != "" && strings.HasSuffix(urlPath, "/") {
+				splat += "/"
+			}
+			vars["splat"] = splat
+			return vars, true
+		}
+		if i >= len(segs) {
+			return nil, false
+		}
+		switch {
+		case strings.HasPrefix(want, ":"):
+			vars[want[1:]] = segs[i]
+		case want != segs[i]:
+			return nil, false
+		}
+	}
+	return vars, len(segs) == len(p)
+}
+
+// trimSlash drops a trailing slash, since Netlify treats /about and /about/
+// as the same path when matching rules.
+func trimSlash(s string) string {
+	if len(s) > 1 {
+		return strings.TrimSuffix(s, "/")
+	}
+	return s
+}
+
+// parseRedirects reads a _redirects file. Rules with conditions (such as
+// Country=) cannot be evaluated locally and are skipped.
+func parseRedirects(data []byte) ([]redirect, error) {
+	var rules []redirect
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("_redirects:%d: want \"from to [status]\"", n)
+		}
+		if len(fields) > 3 {
+			continue
+		}
+
+		rule := redirect{from: parsePattern(fields[0]), to: fields[1], status: 301}
+		if len(fields) == 3 {
+			code, force := strings.CutSuffix(fields[2], "!")
+			status, err := strconv.Atoi(code)
+			if err != nil {
+				continue // a condition rather than a status
+			}
+			rule.status, rule.force = status, force
+		}
+		rules = append(rules, rule)
+	}
+	return rules, sc.Err()
+}
+
+// target returns the destination of r for a matched path, substituting
+// placeholders and the splat.
+func (r redirect) target(vars map[string]string) string {
+	to := r.to
+	for name, value := range vars {
+		to = strings.ReplaceAll(to, ":"+name, value)
+	}
+	return to
+}
+
+// parseHeaders reads a _headers file: a path on its own line, followed by
+// indented "Name: value" lines.
+func parseHeaders(data []byte) ([]headerRule, error) {
+	var rules []headerRule
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		raw := sc.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if raw[0] != ' ' && raw[0] != '\t' {
+			rules = append(rules, headerRule{path: parsePattern(line)})
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || len(rules) == 0 {
+			return nil, fmt.Errorf("_headers:%d: want an indented \"Name: value\" under a path", n)
+		}
+		last := &rules[len(rules)-1]
+		last.headers = append(last.headers, [2]string{strings.TrimSpace(name), strings.TrimSpace(value)})
+	}
+	return rules, sc.Err()
+}
+
+func readOptional(file string) ([]byte, error) {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
@@ -0,0 +1,284 @@
+// Package server serves a built site, mimicking the Netlify behaviour the
+// site relies on: _redirects, _headers and a custom 404 page.
+package server
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// encodings are the precompressed siblings the server looks for, in order
+// of preference.
+var encodings = []struct {
+	name string // Content-Encoding token
+	ext  string // sibling file suffix
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// contentTypes override the system MIME table for types it may not know.
+var contentTypes = map[string]string{
+	".md": "text/markdown; charset=utf-8",
+}
+
+// Server serves the files in a built site directory.
+type Server struct {
+	dir       string
+	headers   []headerRule
+	redirects []redirect
+}
+
+// New creates a Server for dir, reading its _redirects and _headers files if
+// present.
+func New(dir string) (*Server, error) {
+	s := &Server{dir: dir}
+
+	data, err := readOptional(filepath.Join(dir, "_redirects"))
+	if err != nil {
+		return nil, err
+	}
+	if s.redirects, err = parseRedirects(data); err != nil {
+		return nil, err
+	}
+
+	data, err = readOptional(filepath.Join(dir, "_headers"))
+	if err != nil {
+		return nil, err
+	}
+	if s.headers, err = parseHeaders(data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler. A client sending Accept: text/markdown
+// gets a page's index.md companion instead of its HTML. When the client
+// accepts it, a precompressed .br or .gz sibling written by the build is
+// served in place of the original, with Content-Encoding and Vary set
+// accordingly.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	urlPath := path.Clean("/" + r.URL.Path)
+	if strings.HasSuffix(r.URL.Path, "/") && urlPath != "/" {
+		urlPath += "/"
+	}
+	s.applyHeaders(w, urlPath)
+
+	status := http.StatusOK
+	for _, rule := range s.redirects {
+		vars, ok := rule.from.match(urlPath)
+		if !ok {
+			continue
+		}
+		if !rule.force && s.exists(urlPath) {
+			break // Netlify lets existing files shadow unforced rules
+		}
+		to := rule.target(vars)
+		switch {
+		case rule.status >= 300 && rule.status < 400:
+			http.Redirect(w, r, to, rule.status)
+			return
+		case strings.Contains(to, "://"):
+			http.Error(w, "cannot proxy "+to+" locally", http.StatusBadGateway)
+			return
+		}
+		urlPath, status = to, rule.status // a rewrite (200) or custom 404
+		break
+	}
+
+	file, ok := s.resolve(urlPath)
+	if !ok {
+		if info, err := os.Stat(s.path(urlPath)); err == nil && info.IsDir() && !strings.HasSuffix(urlPath, "/") {
+			http.Redirect(w, r, urlPath+"/", http.StatusMovedPermanently)
+			return
+		}
+		s.notFound(w, r)
+		return
+	}
+	if status == http.StatusOK && filepath.Base(file) == "index.html" {
+		file = s.negotiate(w, r, file)
+	}
+	s.serveFile(w, r, file, status)
+}
+
+// negotiate returns the index.md companion of an index.html file when the
+// client prefers Markdown, and the HTML file otherwise.
+func (s *Server) negotiate(w http.ResponseWriter, r *http.Request, file string) string {
+	md := filepath.Join(filepath.Dir(file), "index.md")
+	if _, err := os.Stat(md); err != nil {
+		return file
+	}
+	addVary(w.Header(), "Accept")
+	if prefersMarkdown(r.Header.Get("Accept")) {
+		return md
+	}
+	return file
+}
+
+// path maps a URL path to a file path under the site directory.
+func (s *Server) path(urlPath string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(path.Clean("/"+urlPath)))
+}
+
+// resolve returns the file serving urlPath, which is index.html for a
+// directory URL.
+func (s *Server) resolve(urlPath string) (string, bool) {
+	file := s.path(urlPath)
+	if strings.HasSuffix(urlPath, "/") {
+		file = filepath.Join(file, "index.html")
+	}
+	info, err := os.Stat(file)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+	return file, true
+}
+
+func (s *Server) exists(urlPath string) bool {
+	_, ok := s.resolve(urlPath)
+	return ok
+}
+
+func (s *Server) applyHeaders(w http.ResponseWriter, urlPath string) {
+	for _, rule := range s.headers {
+		if _, ok := rule.path.match(urlPath); !ok {
+			continue
+		}
+		for _, h := range rule.headers {
+			w.Header().Add(h[0], h[1])
+		}
+	}
+}
+
+// notFound serves the site's 404.html, or a plain message if it has none.
+func (s *Server) notFound(w http.ResponseWriter, r *http.Request) {
+	file := filepath.Join(s.dir, "404.html")
+	if _, err := os.Stat(file); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.serveFile(w, r, file, http.StatusNotFound)
+}
+
+// serveFile writes file with the given status. Successful responses go
+// through http.ServeContent so that conditional and range requests work.
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request, file string, status int) {
+	h := w.Header()
+	ext := filepath.Ext(file)
+	if ctype, ok := contentTypes[ext]; ok {
+		h.Set("Content-Type", ctype)
+	} else if ctype := mime.TypeByExtension(ext); ctype != "" {
+		h.Set("Content-Type", ctype)
+	}
+
+	name := file
+	for _, enc := range encodings {
+		if _, err := os.Stat(file + enc.ext); err != nil {
+			continue
+		}
+		// A sibling exists, so the response depends on Accept-Encoding
+		// whether or not this client gets it.
+		addVary(h, "Accept-Encoding")
+		if accepts(r.Header.Get("Accept-Encoding"), enc.name) {
+			name = file + enc.ext
+			h.Set("Content-Encoding", enc.name)
+			break
+		}
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if status == http.StatusOK {
+		http.ServeContent(w, r, filepath.Base(file), info.ModTime(), f)
+		return
+	}
+	h.Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		io.Copy(w, f)
+	}
+}
+
+// addVary adds field to the Vary header unless it is already listed, for
+// example by a _headers rule.
+func addVary(h http.Header, field string) {
+	for _, v := range h.Values("Vary") {
+		for f := range strings.SplitSeq(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(f), field) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", field)
+}
+
+// accepts reports whether an Accept-Encoding header value allows coding. An
+// explicit entry for coding takes precedence over a "*" wildcard.
+func accepts(header, coding string) bool {
+	q, ok := quality(header, coding)
+	if !ok {
+		q, _ = quality(header, "*")
+	}
+	return q > 0
+}
+
+// prefersMarkdown reports whether an Accept header asks for text/markdown
+// explicitly, at least as strongly as it asks for HTML. Wildcards don't
+// count for Markdown, so browsers sending */* still get HTML.
+func prefersMarkdown(header string) bool {
+	md, ok := quality(header, "text/markdown")
+	if !ok || md == 0 {
+		return false
+	}
+	html, ok := quality(header, "text/html")
+	if !ok {
+		if html, ok = quality(header, "text/*"); !ok {
+			html, _ = quality(header, "*/*")
+		}
+	}
+	return md >= html
+}
+
+// quality returns the q-value given to token in a comma-separated header
+// such as Accept or Accept-Encoding, and whether it is listed at all.
+func quality(header, token string) (float64, bool) {
+	for part := range strings.SplitSeq(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), token) {
+			continue
+		}
+		q := 1.0
+		for param := range strings.SplitSeq(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(k, "q") {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		return q, true
+	}
+	return 0, false
+}
@@ -0,0 +1,260 @@
+package server_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/integralist/integralist.co.uk/internal/server"
+)
+
+func setupSite(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "posts", "hello"), 0o755)
+	os.WriteFile(filepath.Join(dir, "posts", "hello", "index.html"), []byte("<p>hello</p>"), 0o644)
+	os.WriteFile(filepath.Join(dir, "posts", "hello", "index.html.gz"), []byte("gzip-bytes"), 0o644)
+	os.WriteFile(filepath.Join(dir, "posts", "hello", "index.html.br"), []byte("br-bytes"), 0o644)
+	os.WriteFile(filepath.Join(dir, "robots.txt"), []byte("User-agent: *\n"), 0o644)
+	return dir
+}
+
+func newServer(t *testing.T, dir string) *server.Server {
+	t.Helper()
+	s, err := server.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func get(h http.Handler, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestServer_Precompressed(t *testing.T) {
+	h := newServer(t, setupSite(t))
+
+	tests := map[string]struct {
+		acceptEncoding string
+		wantEncoding   string
+		wantBody       string
+	}{
+		"brotli preferred": {"gzip, deflate, br", "br", "br-bytes"},
+		"gzip only":        {"gzip", "gzip", "gzip-bytes"},
+		"brotli refused":   {"br;q=0, gzip", "gzip", "gzip-bytes"},
+		"wildcard":         {"*", "br", "br-bytes"},
+		"identity":         {"", "", "<p>hello</p>"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/posts/hello/", nil)
+			if tc.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tc.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tc.wantEncoding)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+				t.Errorf("Content-Type = %q, want text/html", got)
+			}
+			if rec.Body.String() != tc.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tc.wantBody)
+			}
+		})
+	}
+}
+
+// Files without compressed siblings are served as-is, without Vary.
+func TestServer_Uncompressed(t *testing.T) {
+	h := newServer(t, setupSite(t))
+	req := httptest.NewRequest(http.MethodGet, "/robots.txt", nil)
+	req.Header.Set("Accept-Encoding", "gzip, br")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Encoding") != "" || rec.Header().Get("Vary") != "" {
+		t.Errorf("headers = %v, want no encoding or Vary", rec.Header())
+	}
+	if rec.Body.String() != "User-agent: *\n" {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+}
+
+func TestServer_NotFound(t *testing.T) {
+	dir := setupSite(t)
+	h := newServer(t, dir)
+	if rec := get(h, "/missing/"); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+
+	os.WriteFile(filepath.Join(dir, "404.html"), []byte("<h1>Lost?</h1>"), 0o644)
+	rec := get(h, "/missing/")
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "<h1>Lost?</h1>" {
+		t.Errorf("got %d %q, want custom 404 page", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/html", got)
+	}
+}
+
+func TestServer_MarkdownContentType(t *testing.T) {
+	dir := setupSite(t)
+	os.WriteFile(filepath.Join(dir, "posts", "hello", "index.md"), []byte("# Hello"), 0o644)
+
+	rec := get(newServer(t, dir), "/posts/hello/index.md")
+	if got := rec.Header().Get("Content-Type"); got != "text/markdown; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/markdown; charset=utf-8", got)
+	}
+}
+
+func TestServer_DirectoryRedirect(t *testing.T) {
+	rec := get(newServer(t, setupSite(t)), "/posts/hello")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/posts/hello/" {
+		t.Errorf("got %d to %q, want 301 to /posts/hello/", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestServer_Redirects(t *testing.T) {
+	dir := setupSite(t)
+	os.WriteFile(filepath.Join(dir, "_redirects"), []byte(`# comment
+/old-post/        /posts/hello/     301
+/blog/*           /posts/:splat     302
+/robots.txt       /elsewhere.txt
+/forced.txt       /robots.txt       200!
+/tags/:tag/feed   /rss.xml          301
+/spa/*            /posts/hello/     200
+/gone/*           /404.html         404
+/geo/             /uk/              302  Country=gb
+`), 0o644)
+	h := newServer(t, dir)
+
+	tests := map[string]struct {
+		target   string
+		status   int
+		location string
+		body     string
+	}{
+		"exact":            {"/old-post/", 301, "/posts/hello/", ""},
+		"trailing slash":   {"/old-post", 301, "/posts/hello/", ""},
+		"splat":            {"/blog/a/b/", 302, "/posts/a/b/", ""},
+		"placeholder":      {"/tags/go/feed", 301, "/rss.xml", ""},
+		"shadowed by file": {"/robots.txt", 200, "", "User-agent: *\n"},
+		"rewrite":          {"/spa/anything", 200, "", "<p>hello</p>"},
+		"conditions skip":  {"/geo/", 404, "", ""},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := get(h, tc.target)
+			if rec.Code != tc.status {
+				t.Errorf("status = %d, want %d", rec.Code, tc.status)
+			}
+			if got := rec.Header().Get("Location"); got != tc.location {
+				t.Errorf("Location = %q, want %q", got, tc.location)
+			}
+			if tc.body != "" && rec.Body.String() != tc.body {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tc.body)
+			}
+		})
+	}
+}
+
+func TestServer_Headers(t *testing.T) {
+	dir := setupSite(t)
+	os.WriteFile(filepath.Join(dir, "_headers"), []byte(`/*
+  X-Frame-Options: DENY
+/posts/*
+  Cache-Control: public, max-age=60
+`), 0o644)
+	h := newServer(t, dir)
+
+	rec := get(h, "/posts/hello/")
+	if rec.Header().Get("X-Frame-Options") != "DENY" || rec.Header().Get("Cache-Control") != "public, max-age=60" {
+		t.Errorf("headers = %v, want both rules applied", rec.Header())
+	}
+	rec = get(h, "/robots.txt")
+	if rec.Header().Get("Cache-Control") != "" {
+		t.Errorf("Cache-Control = %q, want none outside /posts/", rec.Header().Get("Cache-Control"))
+	}
+}
+
+func TestNew_InvalidHeaders(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "_headers"), []byte("  X-Orphan: yes\n"), 0o644)
+	if _, err := server.New(dir); err == nil || !strings.Contains(err.Error(), "_headers:1") {
+		t.Errorf("error = %v, want _headers line error", err)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	h := server.AccessLog(logger, newServer(t, setupSite(t)))
+
+	get(h, "/missing/")
+	line := buf.String()
+	for _, want := range []string{"method=GET", "path=/missing/", "status=404"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("log line %q missing %q", line, want)
+		}
+	}
+}
+
+func TestServer_MarkdownNegotiation(t *testing.T) {
+	dir := setupSite(t)
+	os.Remove(filepath.Join(dir, "posts", "hello", "index.html.br"))
+	os.Remove(filepath.Join(dir, "posts", "hello", "index.html.gz"))
+	os.WriteFile(filepath.Join(dir, "posts", "hello", "index.md"), []byte("# Hello"), 0o644)
+	h := newServer(t, dir)
+
+	tests := map[string]struct {
+		accept string
+		want   string
+	}{
+		"agent":           {"text/markdown", "# Hello"},
+		"agent with html": {"text/markdown, text/html;q=0.9", "# Hello"},
+		"browser":         {"text/html,application/xhtml+xml,*/*;q=0.8", "<p>hello</p>"},
+		"wildcard only":   {"*/*", "<p>hello</p>"},
+		"html preferred":  {"text/html, text/markdown;q=0.5", "<p>hello</p>"},
+		"markdown q=0":    {"text/markdown;q=0", "<p>hello</p>"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/posts/hello/", nil)
+			req.Header.Set("Accept", tc.accept)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Body.String() != tc.want {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tc.want)
+			}
+			if rec.Header().Get("Vary") != "Accept" {
+				t.Errorf("Vary = %q, want Accept", rec.Header().Get("Vary"))
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/hello/", nil)
+	req.Header.Set("Accept", "text/markdown")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Content-Type"); got != "text/markdown; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/markdown; charset=utf-8", got)
+	}
+}
@@ -0,0 +1,156 @@
+// Package stats reports on a site's content: how much there is, how it is
+// tagged, and what is missing.
+package stats
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/integralist/integralist.co.uk/internal/model"
+	"github.com/integralist/integralist.co.uk/internal/parser"
+)
+
+// largestPages is how many pages Report.LargestPages lists.
+const largestPages = 10
+
+// Report is a summary of a site.
+type Report struct {
+	Posts int `json:"posts"`
+	Pages int `json:"pages"`
+	Tags  int `json:"tags"`
+	// Words is the total word count of all posts.
+	Words        int `json:"words"`
+	AverageWords int `json:"average_words"`
+	// AverageReadingTime is the mean reading time of a post, in minutes.
+	AverageReadingTime float64 `json:"average_reading_time"`
+	// PostsPerYear is newest year first.
+	PostsPerYear []Count `json:"posts_per_year"`
+	// PostsPerTag is most used first.
+	PostsPerTag []Count `json:"posts_per_tag"`
+	// OrphanTags are tags used by only one post.
+	OrphanTags []string `json:"orphan_tags"`
+	// MissingDescription lists the URLs of posts without description front
+	// matter, whose description is taken from their summary instead.
+	MissingDescription []string `json:"missing_description"`
+	// MissingImage lists the URLs of posts without image front matter.
+	MissingImage []string `json:"missing_image"`
+	// LargestPages are the biggest rendered HTML pages, largest first.
+	LargestPages []Page `json:"largest_pages"`
+	// Warnings are the problems found loading the content.
+	Warnings []string `json:"warnings"`
+}
+
+// Count is the number of posts for a year or tag.
+type Count struct {
+	Name  string `json:"name"`
+	Posts int    `json:"posts"`
+}
+
+// Page is the size of a rendered page.
+type Page struct {
+	URL   string `json:"url"`
+	Bytes int    `json:"bytes"`
+}
+
+// New reports on site. sizes holds the size in bytes of each rendered page,
+// by URL.
+func New(site *model.Site, sizes map[string]int) (Report, error) {
+	r := Report{
+		Posts:              len(site.Posts),
+		Pages:              len(site.Pages),
+		Tags:               len(site.Tags),
+		OrphanTags:         []string{},
+		MissingDescription: []string{},
+		MissingImage:       []string{},
+		Warnings:           append([]string{}, site.Warnings...),
+	}
+
+	years := make(map[string]int)
+	readingTime := 0
+	for _, post := range site.Posts {
+		r.Words += post.WordCount
+		readingTime += post.ReadingTime
+		years[strconv.Itoa(post.Date.Year())]++
+
+		meta, _, err := parser.ParseFrontMatter(post.SourceMD)
+		if err != nil {
+			return Report{}, fmt.Errorf("parsing %s: %w", post.Slug, err)
+		}
+		if d, _ := meta["description"].(string); d == "" {
+			r.MissingDescription = append(r.MissingDescription, post.URL)
+		}
+		if post.Image == "" {
+			r.MissingImage = append(r.MissingImage, post.URL)
+		}
+	}
+	if len(site.Posts) > 0 {
+		r.AverageWords = r.Words / len(site.Posts)
+		r.AverageReadingTime = float64(readingTime) / float64(len(site.Posts))
+	}
+
+	for year, n := range years {
+		r.PostsPerYear = append(r.PostsPerYear, Count{Name: year, Posts: n})
+	}
+	slices.SortFunc(r.PostsPerYear, func(a, b Count) int { return cmp.Compare(b.Name, a.Name) })
+
+	for _, tag := range site.Tags {
+		r.PostsPerTag = append(r.PostsPerTag, Count{Name: tag.Name, Posts: len(tag.Posts)})
+		if len(tag.Posts) == 1 {
+			r.OrphanTags = append(r.OrphanTags, tag.Name)
+		}
+	}
+	slices.SortStableFunc(r.PostsPerTag, func(a, b Count) int { return cmp.Compare(b.Posts, a.Posts) })
+
+	for url, n := range sizes {
+		r.LargestPages = append(r.LargestPages, Page{URL: url, Bytes: n})
+	}
+	slices.SortFunc(r.LargestPages, func(a, b Page) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(a.URL, b.URL))
+	})
+	if len(r.LargestPages) > largestPages {
+		r.LargestPages = r.LargestPages[:largestPages]
+	}
+	return r, nil
+}
+
+// WriteText writes the report as aligned plain text.
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Posts\t%d\n", r.Posts)
+	fmt.Fprintf(tw, "Pages\t%d\n", r.Pages)
+	fmt.Fprintf(tw, "Tags\t%d\n", r.Tags)
+	fmt.Fprintf(tw, "Words\t%d (%d per post)\n", r.Words, r.AverageWords)
+	fmt.Fprintf(tw, "Reading time\t%.1f min per post\n", r.AverageReadingTime)
+
+	fmt.Fprintln(tw, "\nPosts per year")
+	for _, c := range r.PostsPerYear {
+		fmt.Fprintf(tw, "  %s\t%d\n", c.Name, c.Posts)
+	}
+	fmt.Fprintln(tw, "\nPosts per tag")
+	for _, c := range r.PostsPerTag {
+		fmt.Fprintf(tw, "  %s\t%d\n", c.Name, c.Posts)
+	}
+	list(tw, "Orphan tags", r.OrphanTags)
+	list(tw, "Posts missing a description", r.MissingDescription)
+	list(tw, "Posts missing an image", r.MissingImage)
+
+	fmt.Fprintln(tw, "\nLargest pages")
+	for _, p := range r.LargestPages {
+		fmt.Fprintf(tw, "  %s\t%d B\n", p.URL, p.Bytes)
+	}
+	if len(r.Warnings) > 0 {
+		list(tw, "Warnings", r.Warnings)
+	}
+	return tw.Flush()
+}
+
+func list(w io.Writer, heading string, items []string) {
+	fmt.Fprintf(w, "\n%s (%d)\n", heading, len(items))
+	for _, item := range items {
+		fmt.Fprintf(w, "  %s\n", item)
+	}
+}
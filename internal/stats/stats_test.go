@@ -0,0 +1,85 @@
+package stats_test
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/integralist/integralist.co.uk/internal/model"
+	"github.com/integralist/integralist.co.uk/internal/stats"
+)
+
+func TestNew(t *testing.T) {
+	a := &model.Post{
+		Date:        time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		Image:       "/a.png",
+		ReadingTime: 4,
+		SourceMD:    []byte("---\ntitle: A\ndescription: Written by hand.\n---\nBody"),
+		URL:         "/posts/a/",
+		WordCount:   600,
+	}
+	b := &model.Post{
+		Date:        time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		ReadingTime: 1,
+		SourceMD:    []byte("---\ntitle: B\n---\nBody"),
+		URL:         "/posts/b/",
+		WordCount:   100,
+	}
+	c := &model.Post{
+		Date:        time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		ReadingTime: 2,
+		SourceMD:    []byte("---\ntitle: C\ndescription: Also by hand.\n---\nBody"),
+		URL:         "/posts/c/",
+		WordCount:   200,
+	}
+	site := &model.Site{
+		Posts: []*model.Post{a, c, b},
+		Tags: []*model.Tag{
+			{Name: "rust", Posts: []*model.Post{a}},
+			{Name: "go", Posts: []*model.Post{a, b}},
+		},
+	}
+	sizes := map[string]int{"/": 300, "/posts/a/": 500, "/posts/b/": 100}
+
+	r, err := stats.New(site, sizes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if r.Posts != 3 || r.Words != 900 || r.AverageWords != 300 {
+		t.Errorf("Posts, Words, AverageWords = %d, %d, %d, want 3, 900, 300", r.Posts, r.Words, r.AverageWords)
+	}
+	if r.AverageReadingTime != 7.0/3 {
+		t.Errorf("AverageReadingTime = %v, want %v", r.AverageReadingTime, 7.0/3)
+	}
+	if want := []stats.Count{{"2025", 2}, {"2024", 1}}; !slices.Equal(r.PostsPerYear, want) {
+		t.Errorf("PostsPerYear = %v, want %v", r.PostsPerYear, want)
+	}
+	if want := []stats.Count{{"go", 2}, {"rust", 1}}; !slices.Equal(r.PostsPerTag, want) {
+		t.Errorf("PostsPerTag = %v, want %v", r.PostsPerTag, want)
+	}
+	if want := []string{"rust"}; !slices.Equal(r.OrphanTags, want) {
+		t.Errorf("OrphanTags = %v, want %v", r.OrphanTags, want)
+	}
+	if want := []string{"/posts/b/"}; !slices.Equal(r.MissingDescription, want) {
+		t.Errorf("MissingDescription = %v, want %v", r.MissingDescription, want)
+	}
+	if want := []string{"/posts/c/", "/posts/b/"}; !slices.Equal(r.MissingImage, want) {
+		t.Errorf("MissingImage = %v, want %v", r.MissingImage, want)
+	}
+	if want := []stats.Page{{"/posts/a/", 500}, {"/", 300}, {"/posts/b/", 100}}; !slices.Equal(r.LargestPages, want) {
+		t.Errorf("LargestPages = %v, want %v", r.LargestPages, want)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText error: %v", err)
+	}
+	for _, want := range []string{"Words         900 (300 per post)", "Orphan tags (1)\n  rust\n", "/posts/a/  500 B"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("text report missing %q:\n%s", want, buf.String())
+		}
+	}
+}
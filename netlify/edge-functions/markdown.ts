@@ -0,0 +1,53 @@
+// Serves a page's index.md companion to clients that send
+// `Accept: text/markdown`, mirroring the negotiation in cmd/server.
+// Netlify _redirects rules can't match on request headers, so this runs as
+// an edge function instead.
+import type { Config, Context } from "@netlify/edge-functions";
+
+export default async (request: Request, context: Context) => {
+  const url = new URL(request.url);
+  if (!url.pathname.endsWith("/") || !prefersMarkdown(request.headers.get("accept") ?? "")) {
+    return;
+  }
+
+  const md = await fetch(new URL("index.md", url));
+  if (!md.ok) {
+    return; // not every page has a companion, e.g. tag pages
+  }
+  return new Response(md.body, {
+    headers: {
+      "content-type": "text/markdown; charset=utf-8",
+      "vary": "Accept",
+    },
+  });
+};
+
+// prefersMarkdown reports whether the Accept header lists text/markdown
+// explicitly, at least as strongly as HTML. Wildcards don't count, so
+// browsers sending */* still get HTML.
+function prefersMarkdown(accept: string): boolean {
+  const md = quality(accept, "text/markdown");
+  if (md === undefined || md === 0) {
+    return false;
+  }
+  const html = quality(accept, "text/html") ?? quality(accept, "text/*") ?? quality(accept, "*/*") ?? 0;
+  return md >= html;
+}
+
+function quality(header: string, token: string): number | undefined {
+  for (const part of header.split(",")) {
+    const [name, ...params] = part.split(";").map((s) => s.trim());
+    if (name.toLowerCase() !== token) {
+      continue;
+    }
+    const q = params.find((p) => p.toLowerCase().startsWith("q="));
+    const value = q ? parseFloat(q.slice(2)) : 1;
+    return Number.isNaN(value) ? 1 : value;
+  }
+  return undefined;
+}
+
+export const config: Config = {
+  path: "/*",
+  excludedPath: ["/assets/*"],
+};